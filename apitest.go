@@ -13,23 +13,20 @@ package apitest
 
 import (
 	"bytes"
-	"context"
-	"crypto/tls"
-	"crypto/x509"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"mime/multipart"
-	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
 	"strings"
-	"sync/atomic"
 	"text/template"
 	"time"
 
@@ -49,7 +46,6 @@ type AT struct {
 	scheme             string
 	host               string
 	port               string
-	url                url.URL
 	caCertPath         string
 	certFile           string
 	keyFile            string
@@ -65,14 +61,25 @@ type AT struct {
 	header          http.Header
 	cookies         []*http.Cookie
 	param           any
-	paramFormat     string // 结果格式，默认为`json`
+	paramIn         string // 参数位置，Query或Body，默认根据请求方法决定
+	paramFormat     string // 参数格式，默认为`json`
 	file            string // 文件
+	multipart       *Multipart
 	resultWrapper   ResultWrapper
 	result          any
 	resultFormat    string // 结果格式，默认为`json`
+	strict          bool   // 严格模式，校验响应与结果类型一致
 	ates            []any
+	equalOpts       []EqualOption  // Equal的比较选项
 	handlerMap      map[string]any // 如："gin.HandlerFunc", gin.HandlerFunc(nil),
 
+	// 进程内处理请求的handler，设置后不再经过网络
+	handler http.Handler
+
+	// 自定义的client和transport，未设置时使用包级别共享的client
+	client    *http.Client
+	transport http.RoundTripper
+
 	// 请求和响应
 	req     *http.Request
 	reqBody []byte
@@ -87,11 +94,20 @@ type AT struct {
 	// 调试
 	debug bool
 
-	// 慢请求数量
-	slowNum int
+	// 录制和回放，fixtureMode为record或replay
+	fixtureMode string
+	fixtureDir  string
+
+	// 记录请求的HAR，未设置时使用StartHARRecorder开启的全局recorder
+	har *HARRecorder
 
-	// 是否批量压力测试中
-	isPressureBatch bool
+	// 慢请求阈值，默认1s
+	slowThreshold time.Duration
+
+	// 压力测试
+	pressureParam   func(i int) any         // 每次运行的参数
+	pressureHeader  func(i int) http.Header // 每次运行额外的header
+	pressureReports []*PressureReport
 
 	err error
 }
@@ -165,6 +181,39 @@ func (at *AT) SetPort(port string) *AT {
 	return at
 }
 
+// SetClient 设置client，SetClientTimeout设置的超时时间依然生效
+func (at *AT) SetClient(client *http.Client) *AT {
+	if client == nil {
+		at.setErr(fmt.Errorf("nil client"))
+		return at
+	}
+
+	at.client = client
+	return at
+}
+
+// SetTransport 设置transport，以它新建client
+func (at *AT) SetTransport(transport http.RoundTripper) *AT {
+	if transport == nil {
+		at.setErr(fmt.Errorf("nil transport"))
+		return at
+	}
+
+	at.transport = transport
+	return at
+}
+
+// SetHandler 设置handler，如*gin.Engine；设置后请求直接在进程内交由handler处理，无需启动服务
+func (at *AT) SetHandler(handler http.Handler) *AT {
+	if handler == nil {
+		at.setErr(fmt.Errorf("nil handler"))
+		return at
+	}
+
+	at.handler = handler
+	return at
+}
+
 // SetHeader 设置header
 func (at *AT) SetHeader(header http.Header) *AT {
 	at.header = header
@@ -194,7 +243,19 @@ func (at *AT) SetParam(param any) *AT {
 	return at
 }
 
-// SetFile 设置文件
+// ParamInQuery 指定参数放在查询字符串里
+func (at *AT) ParamInQuery() *AT {
+	at.paramIn = paramInQuery
+	return at
+}
+
+// ParamInBody 指定参数放在请求体里
+func (at *AT) ParamInBody() *AT {
+	at.paramIn = paramInBody
+	return at
+}
+
+// SetFile 设置文件，表单字段名为file；需要多个文件或字段时使用SetMultipart
 func (at *AT) SetFile(file string) *AT {
 	if file == "" {
 		at.setErr(fmt.Errorf("empty file"))
@@ -246,7 +307,7 @@ func (at *AT) UseXMLFormat() *AT {
 
 // UseXMLParamFormat 设置参数格式为XML
 func (at *AT) UseXMLParamFormat() *AT {
-	at.resultFormat = "xml"
+	at.paramFormat = "xml"
 	return at
 }
 
@@ -283,83 +344,189 @@ func (at *AT) MonkeyRun() *AT {
 	return at.run(true)
 }
 
-// PressureRun 压力运行，n: 运行次数，c: 并发数
-func (at *AT) PressureRun(n, c int) *AT {
-	w := do.NewWorker(c)
-	w.Start()
-
-	// 记录开始时间
-	before := time.Now()
+// SetSlowThreshold 设置慢请求阈值，默认1s
+func (at *AT) SetSlowThreshold(threshold time.Duration) *AT {
+	at.slowThreshold = threshold
+	return at
+}
 
-	var total int64
-	for i := 0; i < n; i++ {
-		if err := w.Push(*do.NewJob(func(ctx context.Context) error {
-			// 运行
-			at.run(true)
+// Debug 开启调试模式
+func (at *AT) Debug() *AT {
+	at.debug = true
+	return at
+}
 
-			// 统计数量
-			atomic.AddInt64(&total, 1)
+// EqualCode 比较响应码
+func (at *AT) EqualCode(wantCode int) *AT {
+	// 复制resp.Body数据
+	data, _, err := copyResponseBody(at.resp)
+	if err != nil {
+		at.setErr(err)
+		return at
+	}
 
-			return nil
-		}, 0, nil)); err != nil {
-			at.setErr(err)
-		}
+	// 校验响应码
+	if at.resp.StatusCode == wantCode {
+		return at
 	}
 
-	w.Stop()
+	at.setErr(fmt.Errorf("bad status code, got %+v\ndata is %s", at.resp, data))
+	return at
+}
 
-	// 记录结束时间，并计算耗时
-	used := time.Since(before)
-	avg := float64(total) / float64(used.Milliseconds()) * 1000
-	fmt.Printf("\n=== Pressure Report ===\nNumber: %d\nConcurrency: %d\nCompleted: %d\nUsed time: %vs\nRPS: %v\n=== END ===\n\n", n, c, total, do.Round(used.Seconds(), 2), do.Round(avg, 2))
+// EqualHeader 比较响应头
+func (at *AT) EqualHeader(key, want string) *AT {
+	if at.resp == nil {
+		at.setErr(fmt.Errorf("nil response"))
+		return at
+	}
 
+	if got := at.resp.Header.Get(key); got != want {
+		at.setErr(fmt.Errorf("header %q not equal, have %q, want %q", key, got, want))
+		return at
+	}
 	return at
 }
 
-// PressureParam 压力测试参数
-type PressureParam struct {
-	N int // 运行次数
-	C int // 并发数
+// HeaderContains 校验响应头的值包含sub，有多个值时任意一个包含即可
+func (at *AT) HeaderContains(key, sub string) *AT {
+	if at.resp == nil {
+		at.setErr(fmt.Errorf("nil response"))
+		return at
+	}
+
+	values := at.resp.Header.Values(key)
+	for _, v := range values {
+		if strings.Contains(v, sub) {
+			return at
+		}
+	}
+	at.setErr(fmt.Errorf("header %q doesn't contain %q, have %q", key, sub, values))
+	return at
 }
 
-// PressureRunBatch 批量压力运行
-func (at *AT) PressureRunBatch(param []PressureParam) *AT {
-	at.isPressureBatch = true
+// EqualContentType 比较响应的Content-Type；want不带参数时只比较媒体类型，如"application/json"可以匹配"application/json; charset=utf-8"
+func (at *AT) EqualContentType(want string) *AT {
+	if at.resp == nil {
+		at.setErr(fmt.Errorf("nil response"))
+		return at
+	}
 
-	for _, single := range param {
-		at = at.PressureRun(single.N, single.C)
+	got := at.resp.Header.Get("Content-Type")
+	wantType, wantParams, err := mime.ParseMediaType(want)
+	if err != nil {
+		at.setErr(fmt.Errorf("bad content type %q: %w", want, err))
+		return at
+	}
+	gotType, gotParams, err := mime.ParseMediaType(got)
+	if err != nil {
+		at.setErr(fmt.Errorf("content type not equal, have %q, want %q", got, want))
+		return at
+	}
+	if gotType != wantType {
+		at.setErr(fmt.Errorf("content type not equal, have %q, want %q", got, want))
+		return at
+	}
+	for k, v := range wantParams {
+		if !strings.EqualFold(gotParams[k], v) {
+			at.setErr(fmt.Errorf("content type not equal, have %q, want %q", got, want))
+			return at
+		}
 	}
+	return at
+}
 
-	fmt.Printf("slowNum is %d\n", at.slowNum)
-	at.isPressureBatch = false
+// EqualCookie 比较响应设置的cookie的值
+func (at *AT) EqualCookie(name, want string) *AT {
+	cookie, err := at.respCookie(name)
+	if err != nil {
+		at.setErr(err)
+		return at
+	}
 
+	if cookie.Value != want {
+		at.setErr(fmt.Errorf("cookie %q not equal, have %q, want %q", name, cookie.Value, want))
+		return at
+	}
 	return at
 }
 
-// Debug 开启调试模式
-func (at *AT) Debug() *AT {
-	at.debug = true
-	return at
+type CookieFlag int
+
+const (
+	CookieHttpOnly       CookieFlag = iota + 1 // HttpOnly
+	CookieSecure                               // Secure
+	CookieSameSiteLax                          // SameSite=Lax
+	CookieSameSiteStrict                       // SameSite=Strict
+	CookieSameSiteNone                         // SameSite=None
+)
+
+func (f CookieFlag) String() string {
+	r := ""
+	switch f {
+	case CookieHttpOnly:
+		r = "HttpOnly"
+	case CookieSecure:
+		r = "Secure"
+	case CookieSameSiteLax:
+		r = "SameSite=Lax"
+	case CookieSameSiteStrict:
+		r = "SameSite=Strict"
+	case CookieSameSiteNone:
+		r = "SameSite=None"
+	}
+	return r
 }
 
-// EqualCode 比较响应码
-func (at *AT) EqualCode(wantCode int) *AT {
-	// 复制resp.Body数据
-	data, _, err := copyResponseBody(at.resp)
+// SetsCookie 校验响应设置了名为name的cookie，并且带有全部flags，如：SetsCookie("session", CookieHttpOnly, CookieSecure)
+func (at *AT) SetsCookie(name string, flags ...CookieFlag) *AT {
+	cookie, err := at.respCookie(name)
 	if err != nil {
 		at.setErr(err)
 		return at
 	}
 
-	// 校验响应码
-	if at.resp.StatusCode == wantCode {
-		return at
+	for _, flag := range flags {
+		var ok bool
+		switch flag {
+		case CookieHttpOnly:
+			ok = cookie.HttpOnly
+		case CookieSecure:
+			ok = cookie.Secure
+		case CookieSameSiteLax:
+			ok = cookie.SameSite == http.SameSiteLaxMode
+		case CookieSameSiteStrict:
+			ok = cookie.SameSite == http.SameSiteStrictMode
+		case CookieSameSiteNone:
+			ok = cookie.SameSite == http.SameSiteNoneMode
+		}
+		if !ok {
+			at.setErr(fmt.Errorf("cookie %q doesn't have %s, have %q", name, flag, cookie.String()))
+			return at
+		}
 	}
-
-	at.setErr(fmt.Errorf("bad status code, got %+v\ndata is %s", at.resp, data))
 	return at
 }
 
+func (at *AT) respCookie(name string) (*http.Cookie, error) {
+	if at.resp == nil {
+		return nil, fmt.Errorf("nil response")
+	}
+
+	cookies := at.resp.Cookies()
+	for _, cookie := range cookies {
+		if cookie.Name == name {
+			return cookie, nil
+		}
+	}
+
+	names := make([]string, 0, len(cookies))
+	for _, cookie := range cookies {
+		names = append(names, cookie.Name)
+	}
+	return nil, fmt.Errorf("cookie %q not found, have %q", name, names)
+}
+
 var (
 	resultExtractor = make(map[string]ResultExtractor)
 )
@@ -378,6 +545,142 @@ func (at *AT) GetResultExtractor(format string) (re ResultExtractor, ok bool) {
 	return re, ok
 }
 
+var (
+	paramEncoder = make(map[string]paramEncoderEntry)
+)
+
+type (
+	// ParamEncoder 将参数编码为请求体
+	ParamEncoder func(param any) ([]byte, error)
+
+	// ParamFormatter 将编码后的请求体转为json，用于在文档里展示示例
+	ParamFormatter func(data []byte) ([]byte, error)
+
+	paramEncoderEntry struct {
+		encoder     ParamEncoder
+		contentType string
+		formatter   ParamFormatter
+	}
+)
+
+// RegisterParamEncoder 注册参数格式的编码器，使用SetParamFormat选择；也可以覆盖json、xml等内置格式
+func (at *AT) RegisterParamEncoder(format string, encoder ParamEncoder, contentType string) *AT {
+	entry := paramEncoder[format]
+	entry.encoder = encoder
+	entry.contentType = contentType
+	paramEncoder[format] = entry
+	return at
+}
+
+// RegisterParamFormatter 注册参数格式在文档里的展示方式，未注册时展示参数的json
+func (at *AT) RegisterParamFormatter(format string, formatter ParamFormatter) *AT {
+	entry := paramEncoder[format]
+	entry.formatter = formatter
+	paramEncoder[format] = entry
+	return at
+}
+
+func (at *AT) GetParamEncoder(format string) (pe ParamEncoder, contentType string, ok bool) {
+	entry, ok := paramEncoder[format]
+	if !ok || entry.encoder == nil {
+		return nil, "", false
+	}
+	return entry.encoder, entry.contentType, true
+}
+
+// SetParamFormat 设置参数格式，如：xml、form或RegisterParamEncoder注册的格式
+func (at *AT) SetParamFormat(format string) *AT {
+	at.paramFormat = format
+	return at
+}
+
+// SetResultFormat 设置结果格式，如：xml或RegisterResultExtractor注册的格式
+func (at *AT) SetResultFormat(format string) *AT {
+	at.resultFormat = format
+	return at
+}
+
+func encodeParam(format string, param any) ([]byte, error) {
+	if entry, ok := paramEncoder[format]; ok && entry.encoder != nil {
+		data, err := entry.encoder(param)
+		if err != nil {
+			return nil, fmt.Errorf("%s encode failed: %w", format, err)
+		}
+		return data, nil
+	}
+
+	switch format {
+	case "xml":
+		return xml.Marshal(param)
+	case protobufFormat:
+		return protoMarshal(param)
+	case formParamFormat:
+		values, err := formValues(param)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(values.Encode()), nil
+	case "", "json":
+		return json.Marshal(param)
+	}
+	return nil, fmt.Errorf("unknown param format %q, please RegisterParamEncoder first", format)
+}
+
+func paramContentType(format string) string {
+	if entry, ok := paramEncoder[format]; ok && entry.encoder != nil {
+		return entry.contentType
+	}
+
+	switch format {
+	case "xml":
+		return "application/xml; charset=utf-8"
+	case formParamFormat:
+		return formContentType
+	case protobufFormat:
+		return protobufContentType
+	}
+	return "application/json; charset=utf-8"
+}
+
+// isBuiltinParamFormat 内置的文本格式且没有被覆盖
+func isBuiltinParamFormat(format string) bool {
+	if entry, ok := paramEncoder[format]; ok && entry.encoder != nil {
+		return false
+	}
+	switch format {
+	case "", "json", "xml", formParamFormat:
+		return true
+	}
+	return false
+}
+
+// paramExample 文档里的参数示例，自定义格式优先使用注册的formatter转为json，否则使用参数本身的json
+func (at *AT) paramExample() (data []byte, format string, isJSON bool, err error) {
+	if isBuiltinParamFormat(at.paramFormat) {
+		return at.reqBody, at.paramFormat, at.paramFormat != formParamFormat, nil
+	}
+
+	if at.paramFormat == protobufFormat && at.param != nil {
+		data, err = protoJSON(at.param)
+		return data, "json", true, err
+	}
+	if entry := paramEncoder[at.paramFormat]; entry.formatter != nil {
+		data, err = entry.formatter(at.reqBody)
+		if err != nil {
+			return nil, "", false, fmt.Errorf("%s format failed: %w", at.paramFormat, err)
+		}
+		return data, "json", true, nil
+	}
+	if at.param == nil {
+		return nil, "json", true, nil
+	}
+	data, err = json.Marshal(at.param)
+	if err != nil {
+		return nil, "", false, err
+	}
+	return data, "json", true, nil
+}
+
 func extract(format string, data []byte, r any) error {
 	re, ok := resultExtractor[format]
 	if ok {
@@ -389,6 +692,8 @@ func extract(format string, data []byte, r any) error {
 		if err := xml.Unmarshal(data, r); err != nil {
 			return fmt.Errorf("xml decode failed: %+v, data: %s", err, data)
 		}
+	case protobufFormat:
+		return protoUnmarshal(data, r)
 	default:
 		if err := json.Unmarshal(data, r); err != nil {
 			return fmt.Errorf("json decode failed: %+v, data: %s", err, data)
@@ -424,6 +729,14 @@ func (at *AT) Result(r any) *AT {
 			return at
 		}
 
+		// 严格模式下先校验响应的字段和类型，以便一次列出所有问题
+		if at.strict && (at.resultFormat == "" || at.resultFormat == "json") {
+			if err := checkStrict(data, r); err != nil {
+				at.setErr(err)
+				return at
+			}
+		}
+
 		// 解析data到r
 		if err := extract(at.resultFormat, data, r); err != nil {
 			at.setErr(err)
@@ -459,8 +772,14 @@ func (at *AT) Equal(args ...any) *AT {
 		return at
 	}
 	for i := 0; i < l; i += 2 {
-		if !reflect.DeepEqual(args[i], args[i+1]) {
-			at.setErr(fmt.Errorf("no.%d Not Equal, Have %v, Want %v", i/2+1, args[i], args[i+1]))
+		if len(at.equalOpts) == 0 && reflect.DeepEqual(args[i], args[i+1]) {
+			continue
+		}
+		if diffs := diffValue(args[i], args[i+1], at.equalOpts...); len(diffs) > 0 {
+			if len(diffs) > maxEqualDiffs {
+				diffs = append(diffs[:maxEqualDiffs], fmt.Sprintf("... and %d more", len(diffs)-maxEqualDiffs))
+			}
+			at.setErr(fmt.Errorf("no.%d Not Equal:\n%s", i/2+1, strings.Join(diffs, "\n")))
 			return at
 		}
 	}
@@ -541,7 +860,8 @@ func (at *AT) Err() error {
 
 // === Private method ===
 
-func (at *AT) makeURL() *AT {
+// buildURL 根据服务器配置和路径生成请求链接
+func (at *AT) buildURL() url.URL {
 	// 默认值
 	scheme := "http"
 	host := "localhost"
@@ -570,77 +890,121 @@ func (at *AT) makeURL() *AT {
 	} else {
 		realHost = host + port
 	}
-	at.url = url.URL{
+	return url.URL{
 		Scheme:   scheme,
 		Host:     realHost,
 		Path:     path,
 		RawQuery: query,
 	}
+}
 
-	return at
+const (
+	paramInQuery = "Query"
+	paramInBody  = "Body"
+)
+
+// paramPosition 参数位置，未明确指定时，GET、DELETE、HEAD、OPTIONS等方法放在查询字符串里，其余方法放在请求体里
+func (at *AT) paramPosition() string {
+	if at.multipart != nil {
+		return paramInBody
+	}
+	if at.paramIn != "" {
+		return at.paramIn
+	}
+	return defaultParamIn(at.method)
+}
+
+func defaultParamIn(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodDelete, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodConnect:
+		return paramInQuery
+	}
+	return paramInBody
 }
 
 func (at *AT) run(realDo bool) *AT {
+	ex := at.newExchange()
+	if err := at.prepare(ex); err != nil {
+		at.setErr(err)
+		return at
+	}
+	at.req = ex.req
+	at.reqBody = ex.reqBody
+
+	if realDo {
+		at.send(ex)
+		if r := at.getHARRecorder(); r != nil {
+			r.add(at, ex)
+		}
+		if ex.err != nil {
+			at.setErr(ex.err)
+			return at
+		}
+		if ex.used >= at.getSlowThreshold() {
+			fmt.Printf("WARNING: '%s' is slow, used %d ms\n", ex.req.URL.String(), ex.used.Milliseconds())
+		}
+
+		at.resp = ex.resp
+	}
+
+	return at
+}
+
+// exchange 一次请求的上下文；压力测试时每次运行各自持有一个，互不干扰
+type exchange struct {
+	i int // 第几次运行
+
+	param  any
+	header http.Header
+
+	req     *http.Request
+	reqBody []byte
+	resp    *http.Response
+	start   time.Time
+	used    time.Duration
+	err     error
+}
+
+func (at *AT) newExchange() *exchange {
+	return &exchange{
+		param:  at.param,
+		header: at.header,
+	}
+}
+
+// prepare 根据参数新建请求
+func (at *AT) prepare(ex *exchange) error {
 	// 请求链接
-	at = at.makeURL()
-	u := at.url
+	u := at.buildURL()
 
 	// 参数处理
 	var body = new(bytes.Buffer)
-	switch at.method {
-	case http.MethodGet, http.MethodDelete:
+	switch at.paramPosition() {
+	case paramInQuery:
 		q := u.Query()
-		if at.param != nil {
-			params, err := structToMap(at.param)
+		if ex.param != nil {
+			values, err := paramValues(ex.param)
 			if err != nil {
-				at.setErr(err)
-				return at
+				return err
 			}
-			var valueStr string
-			for key, value := range params {
-				switch v := value.(type) { // 类型断言，既不能用逗号分隔，也不可用fallthrough
-				case []int: // 整型数组
-					for _, s := range v {
-						valueStr = fmt.Sprintf("%v", s)
-						q.Add(key, valueStr)
-					}
-				case []string: // 字符串数组
-					for _, s := range v {
-						valueStr = fmt.Sprintf("%v", s)
-						q.Add(key, valueStr)
-					}
-				default:
-					valueStr = fmt.Sprintf("%v", value)
-					q.Add(key, valueStr)
+			for key, value := range values {
+				for _, v := range value {
+					q.Add(key, v)
 				}
 			}
 		}
 		u.RawQuery = q.Encode()
-	case http.MethodPost, http.MethodPut:
-		var paramBytes []byte
-		var err error
-		switch at.paramFormat {
-		case "xml":
-			paramBytes, err = xml.Marshal(at.param)
+	default:
+		if ex.param != nil {
+			paramBytes, err := encodeParam(at.paramFormat, ex.param)
 			if err != nil {
-				at.setErr(err)
-				return at
+				return err
 			}
-		default:
-			paramBytes, err = json.Marshal(at.param)
+			_, err = body.Write(paramBytes)
 			if err != nil {
-				at.setErr(err)
-				return at
+				return err
 			}
 		}
-		_, err = body.Write(paramBytes)
-		if err != nil {
-			at.setErr(err)
-			return at
-		}
-	default:
-		at.setErr(fmt.Errorf("not support method %s", at.method))
-		return at
 	}
 
 	// 文件内容
@@ -648,8 +1012,7 @@ func (at *AT) run(realDo bool) *AT {
 	if at.file != "" {
 		f, err := os.OpenFile(at.file, os.O_RDONLY, os.ModePerm)
 		if err != nil {
-			at.setErr(err)
-			return at
+			return err
 		}
 		defer f.Close()
 
@@ -659,25 +1022,31 @@ func (at *AT) run(realDo bool) *AT {
 		// this step is very important
 		fileWriter, err := bodyWriter.CreateFormFile("file", at.file)
 		if err != nil {
-			at.setErr(err)
-			return at
+			return err
 		}
 
 		//iocopy
 		_, err = io.Copy(fileWriter, f)
 		if err != nil {
-			at.setErr(err)
-			return at
+			return err
 		}
 
 		fileContentType = bodyWriter.FormDataContentType()
 		bodyWriter.Close()
 	}
+	if at.multipart != nil {
+		body.Reset()
+		contentType, err := at.multipart.writeTo(body, ex.param)
+		if err != nil {
+			return err
+		}
+		fileContentType = contentType
+	}
 
 	// 复制一份请求body
 	reqBody := make([]byte, body.Len())
 	copy(reqBody, body.Bytes())
-	at.reqBody = reqBody
+	ex.reqBody = reqBody
 
 	if at.debug {
 		fmt.Printf("will do request %s %s with body %s\n", at.method, u.String(), body.String())
@@ -686,23 +1055,17 @@ func (at *AT) run(realDo bool) *AT {
 	// 新建请求
 	req, err := http.NewRequest(at.method, u.String(), body)
 	if err != nil {
-		at.setErr(err)
-		return at
+		return err
 	}
 
 	// 设置header
 	innerHeader := map[string]string{
-		"Content-Type": "application/json; charset=utf-8",
-	}
-	if at.paramFormat == "xml" {
-		innerHeader = map[string]string{
-			"Content-Type": "application/xml; charset=utf-8",
-		}
+		"Content-Type": paramContentType(at.paramFormat),
 	}
 	for headerKey, headerValue := range innerHeader {
 		req.Header.Set(headerKey, headerValue)
 	}
-	for k, v := range at.header {
+	for k, v := range ex.header {
 		for _, vv := range v {
 			req.Header.Set(k, vv)
 		}
@@ -715,79 +1078,66 @@ func (at *AT) run(realDo bool) *AT {
 	for _, c := range at.cookies {
 		req.AddCookie(c)
 	}
-	at.req = req
-
-	var tlsConfig *tls.Config
-	if at.scheme == "https" {
-		if at.insecureSkipVerify {
-			tlsConfig = &tls.Config{
-				InsecureSkipVerify: at.insecureSkipVerify,
-			}
-		} else {
-			caCrt, err := os.ReadFile(at.caCertPath)
-			if err != nil {
-				at.setErr(err)
-				return at
-			}
+	ex.req = req
 
-			pool := x509.NewCertPool()
-			pool.AppendCertsFromPEM(caCrt)
+	return nil
+}
 
-			cliCrt, err := tls.LoadX509KeyPair(at.certFile, at.keyFile)
-			if err != nil {
-				at.setErr(err)
-				return at
-			}
+// send 发起请求，并记录耗时；回放模式下使用保存的响应
+func (at *AT) send(ex *exchange) {
+	ex.start = time.Now()
+	switch at.fixtureMode {
+	case fixtureReplay:
+		ex.resp, ex.err = at.replay(ex)
+	default:
+		ex.resp, ex.err = at.do(ex.req)
+	}
+	ex.used = time.Since(ex.start)
 
-			tlsConfig = &tls.Config{
-				RootCAs:      pool,
-				Certificates: []tls.Certificate{cliCrt},
-			}
-		}
+	if ex.err == nil && at.fixtureMode == fixtureRecord {
+		ex.err = at.record(ex)
 	}
+}
 
-	// 发起请求
-	// https://medium.com/@nate510/don-t-use-go-s-default-http-client-4804cb19f779
-	transport := &http.Transport{
-		Dial: (&net.Dialer{
-			Timeout: 5 * time.Second,
-		}).Dial,
-		TLSHandshakeTimeout: 5 * time.Second,
-		MaxIdleConns:        100, // 最大空闲连接数
-		MaxIdleConnsPerHost: 100, // 每个域名最大空闲连接数
-		TLSClientConfig:     tlsConfig,
+// do 发起请求；设置了handler时在进程内直接处理，不经过网络
+func (at *AT) do(req *http.Request) (*http.Response, error) {
+	if at.handler != nil {
+		return serveHandler(at.handler, req), nil
 	}
-	var clientTimeout = 10 * time.Second
-	if at.clientTimeout != 0 {
-		clientTimeout = at.clientTimeout
+
+	client, err := at.getClient()
+	if err != nil {
+		return nil, err
 	}
-	client := &http.Client{
-		Timeout:   clientTimeout, // 超时
-		Transport: transport,
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
 	}
-	if realDo {
-		beforeDo := time.Now()
-		resp, err := client.Do(req)
-		if err != nil {
-			at.setErr(err)
-			return at
-		}
-		afterDo := time.Now()
-		used := afterDo.UnixNano() - beforeDo.UnixNano()
-		if used >= 1000000000 { // 不小于1s
-			if at.isPressureBatch { // 统计数量
-				at.slowNum++
-			} else {
-				fmt.Printf("WARNING: '%s' is slow, used %d ms\n", u.String(), used/1000000)
-			}
-		}
 
-		// https://stackoverflow.com/questions/17948827/reusing-http-connections-in-golang
-		// 只要不关闭response，client就不会重用连接，而是新建连接
-		at.resp = resp
+	// https://stackoverflow.com/questions/17948827/reusing-http-connections-in-golang
+	// 读完并关闭原始body之后，连接才会被放回连接池重用，这里把body缓存到内存里
+	body := resp.Body
+	defer body.Close()
+	if _, _, err := copyResponseBody(resp); err != nil {
+		return nil, err
 	}
 
-	return at
+	return resp, nil
+}
+
+// serveHandler 使用recorder在进程内执行handler，并将结果转为响应
+func serveHandler(handler http.Handler, req *http.Request) *http.Response {
+	// 补齐服务端请求才有的字段
+	sreq := req.Clone(req.Context())
+	sreq.RequestURI = req.URL.RequestURI()
+	sreq.RemoteAddr = "127.0.0.1:0"
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, sreq)
+
+	resp := rec.Result()
+	resp.Request = req
+	return resp
 }
 
 const (
@@ -903,6 +1253,8 @@ func (at *AT) makeDoc() *AT {
 		switch at.resultFormat {
 		case "xml":
 			resph += "- Content-Type: application/xml; charset=utf-8\n\n"
+		case protobufFormat:
+			resph += "- Content-Type: " + protobufContentType + "\n\n"
 		default:
 			resph += "- Content-Type: application/json; charset=utf-8\n\n"
 		}
@@ -911,7 +1263,18 @@ func (at *AT) makeDoc() *AT {
 
 	// 在解析参数和返回的同时，收集注释信息：map[string]string, 其中key的值需要保留每层的路径，如：|list|name
 	// 参数
-	block, pkcm, err := structToBlock(paramName, at.method, at.param)
+	var block string
+	var pkcm map[string]string
+	var err error
+	if at.multipart != nil {
+		block, pkcm, err = at.multipart.block(at.param)
+	} else {
+		paramFormat := at.paramFormat
+		if at.paramPosition() != paramInBody { // 查询字符串的键与xml tag无关
+			paramFormat = ""
+		}
+		block, pkcm, err = structToBlock(paramName, at.paramLabel(), paramFormat, at.param)
+	}
 	if err != nil {
 		at.setErr(err)
 		return at
@@ -919,7 +1282,7 @@ func (at *AT) makeDoc() *AT {
 	doc += block
 
 	// 返回
-	block, rkcm, err := structToBlock(returnName, at.method, at.result)
+	block, rkcm, err := structToBlock(returnName, "", at.resultFormat, at.result)
 	if err != nil {
 		at.setErr(err)
 		return at
@@ -937,10 +1300,10 @@ func (at *AT) makeDoc() *AT {
 	}
 
 	var paramData []byte
-	switch at.method {
-	case http.MethodGet, http.MethodDelete:
+	switch at.paramPosition() {
+	case paramInQuery:
 		paramData = []byte(at.req.URL.RawQuery)
-	case http.MethodPost, http.MethodPut:
+	default:
 		paramData = at.reqBody
 	}
 
@@ -968,17 +1331,43 @@ func (at *AT) makeDoc() *AT {
 	doc += exampleName + ":\n\n"
 
 	// 参数和返回示例
-	switch at.method {
-	case http.MethodGet, http.MethodDelete:
+	switch at.paramPosition() {
+	case paramInQuery:
 		doc += dataToSummary(paramName, []byte(at.req.URL.RawQuery), at.paramFormat, false, nil)
-	case http.MethodPost, http.MethodPut:
-		isjson := at.file == ""
-		doc += dataToSummary(paramName, at.reqBody, at.paramFormat, isjson, pkcm)
+	default:
+		if at.multipart != nil {
+			summary, err := at.multipart.summary(at.param)
+			if err != nil {
+				at.setErr(err)
+				return at
+			}
+			doc += dataToSummary(paramName, summary, at.paramFormat, false, nil)
+			break
+		}
+		if at.file != "" {
+			doc += dataToSummary(paramName, at.reqBody, at.paramFormat, false, pkcm)
+			break
+		}
+		data, format, isjson, err := at.paramExample()
+		if err != nil {
+			at.setErr(err)
+			return at
+		}
+		doc += dataToSummary(paramName, data, format, isjson, pkcm)
 	}
 
 	// 复制resp.Body
 	var data []byte
-	if at.resp != nil {
+	resultFormat := at.resultFormat
+	if at.resultFormat == protobufFormat {
+		// protobuf的示例使用protojson
+		data, err = protoJSON(at.result)
+		if err != nil {
+			at.setErr(err)
+			return at
+		}
+		resultFormat = "json"
+	} else if at.resp != nil {
 		data, _, err = copyResponseBody(at.resp)
 		if err != nil {
 			at.setErr(err)
@@ -1000,7 +1389,7 @@ func (at *AT) makeDoc() *AT {
 			}
 		}
 	}
-	doc += dataToSummary(returnName, data, at.resultFormat, true, rkcm)
+	doc += dataToSummary(returnName, data, resultFormat, true, rkcm)
 
 	at.doc = doc
 
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"go/ast"
 	"go/parser"
@@ -12,6 +13,7 @@ import (
 	"log"
 	"math/rand"
 	"net/http"
+	"net/url"
 	"os"
 	"reflect"
 	"strconv"
@@ -22,6 +24,7 @@ import (
 	"github.com/donnol/do"
 	"github.com/go-xmlfmt/xmlfmt"
 	"github.com/jaswdr/faker"
+	"google.golang.org/protobuf/proto"
 )
 
 func init() {
@@ -60,7 +63,7 @@ func XMLIndent(w io.Writer, v any) {
 		b = buf.Bytes()
 	} else {
 		var err error
-		b, err = json.Marshal(v)
+		b, err = xml.Marshal(v)
 		if err != nil {
 			panic(err)
 		}
@@ -603,6 +606,31 @@ func structToMap(v any) (map[string]any, error) {
 	return m, nil
 }
 
+// paramValues 将结构体参数转为键值，用于查询字符串；只展开第一层字段，表单和multipart使用formValues
+func paramValues(v any) (url.Values, error) {
+	params, err := structToMap(v)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(url.Values, len(params))
+	for key, value := range params {
+		switch v := value.(type) { // 类型断言，既不能用逗号分隔，也不可用fallthrough
+		case []int: // 整型数组
+			for _, s := range v {
+				values.Add(key, fmt.Sprintf("%v", s))
+			}
+		case []string: // 字符串数组
+			for _, s := range v {
+				values.Add(key, fmt.Sprintf("%v", s))
+			}
+		default:
+			values.Add(key, fmt.Sprintf("%v", value))
+		}
+	}
+	return values, nil
+}
+
 func structTypeValue(v any) (vtype reflect.Type, vvalue reflect.Value, err error) {
 	if v == nil {
 		err = fmt.Errorf("input is nil")
@@ -641,21 +669,11 @@ func toMap(vtype reflect.Type, vvalue reflect.Value, m map[string]any) {
 			}
 			toMap(fieldType, value, m)
 		} else {
-			jsonName := getFieldNameByTag(field.Tag)
-			if jsonName == "-" { // 忽略字段
+			jsonName, ok := fieldName(field)
+			if !ok {
 				continue
 			}
 
-			if jsonName == "" { // 使用默认名
-				jsonName = strings.ToLower(string(field.Name[0])) // 字段名首字母小写
-				if len(field.Name) > 1 {
-					jsonName += field.Name[1:]
-				}
-			} else { // 多个部分时，使用第一个部分
-				split := strings.Split(jsonName, ",")
-				jsonName = strings.TrimSpace(split[0])
-			}
-
 			// 字段类型是指针时，如果值是nil则忽略，如果字段值非nil则取值
 			if field.Type.Kind() == reflect.Ptr {
 				if value.IsNil() {
@@ -735,7 +753,12 @@ func structToList(name string, data ...any) (string, error) {
 	return list, nil
 }
 
-func structToBlock(name, method string, data any) (string, map[string]string, error) {
+// structToBlock 将结构体转为文档块，in为参数位置，如Query、Body；format为xml时按xml tag列出字段
+func structToBlock(name, in, format string, data any) (string, map[string]string, error) {
+	if m, ok := data.(proto.Message); ok {
+		return protoToBlock(name, in, m)
+	}
+
 	var block string
 	var err error
 	var isSlice bool
@@ -762,34 +785,21 @@ func structToBlock(name, method string, data any) (string, map[string]string, er
 		}
 	}
 
-	block += name
-	switch name {
-	case paramName:
-		block += " - "
-		switch method {
-		case http.MethodGet, http.MethodDelete:
-			block += "Query"
-		case http.MethodPost, http.MethodPut:
-			block += "Body"
-		}
-	}
-	id := name + "-" + faker.New().UUID().V4()
-	tmpl := do.Must1(template.New("copyJSON").Parse(copyJSONTmpl))
-	buf := new(bytes.Buffer)
-	do.Must(tmpl.Execute(buf, copyJSON{
-		ButtonId: "button-" + id,
-		TextId:   id,
-		Text:     string(do.Must1(json.Marshal(data))),
-	}))
-	block += buf.String() + "\n\n"
-
 	var level int
 	if isSlice {
 		level++
 	}
 
 	fields := dataStruct.GetFields()
-	lines, kcm := fieldsToLine(level, fields)
+	var lines string
+	var kcm map[string]string
+	if format == "xml" {
+		block += blockTitle(name, in, do.Must1(xml.Marshal(data)))
+		lines, kcm = xmlFieldsToLine(level, fields)
+	} else {
+		block += blockTitle(name, in, do.Must1(json.Marshal(data)))
+		lines, kcm = fieldsToLine(level, fields)
+	}
 	if isSlice {
 		lines = "* (*object list*) 数据列表\n" + lines
 	}
@@ -799,6 +809,23 @@ func structToBlock(name, method string, data any) (string, map[string]string, er
 	return block, kcm, nil
 }
 
+// blockTitle 文档块的标题，附带复制json的按钮
+func blockTitle(name, in string, data []byte) string {
+	title := name
+	if name == paramName && in != "" {
+		title += " - " + in
+	}
+	id := name + "-" + faker.New().UUID().V4()
+	tmpl := do.Must1(template.New("copyJSON").Parse(copyJSONTmpl))
+	buf := new(bytes.Buffer)
+	do.Must(tmpl.Execute(buf, copyJSON{
+		ButtonId: "button-" + id,
+		TextId:   id,
+		Text:     string(data),
+	}))
+	return title + buf.String() + "\n\n"
+}
+
 var (
 	copyJSONTmpl = `&nbsp;<button id="{{.ButtonId}}" onclick="(function() {var copyText = document.getElementById('{{.TextId}}');copyText.select();copyText.setSelectionRange(0, 99999);navigator.clipboard.writeText(copyText.value);var btn = document.getElementById('{{.ButtonId}}');btn.innerHTML='Copied!';btn.style.backgroundColor='powderblue';setTimeout(()=>{btn.innerHTML='Copy JSON';btn.style.backgroundColor='buttonface';}, 5000);})()">Copy JSON</button><textarea id="{{.TextId}}" style="display:none;">{{.Text}}</textarea>`
 )
@@ -830,28 +857,71 @@ func getFieldNameByTag(tag reflect.StructTag) string {
 	return tag.Get("json")
 }
 
+// docField 字段在文档里的名字和选项
+type docField struct {
+	name  string   // 字段名
+	opts  []string // tag里名字之后的部分，如：omitempty、string
+	embed bool     // 没有名字的内嵌结构体，字段提升到上一层
+}
+
+// resolveDocField 文档和严格模式共用的字段规则：tag为-或非导出的字段忽略，没有名字的内嵌结构体展开；返回false时忽略该字段
+func resolveDocField(sf reflect.StructField) (docField, bool) {
+	var r docField
+
+	tag := getFieldNameByTag(sf.Tag)
+	if tag == "-" {
+		return r, false
+	}
+	parts := strings.Split(tag, ",")
+	r.name = strings.TrimSpace(parts[0])
+	for _, part := range parts[1:] {
+		r.opts = append(r.opts, strings.TrimSpace(part))
+	}
+
+	if sf.Anonymous && r.name == "" {
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct {
+			r.embed = true
+			return r, true
+		}
+	}
+	if !sf.IsExported() {
+		return r, false
+	}
+	if r.name == "" {
+		r.name = sf.Name
+	}
+	return r, true
+}
+
+func (f docField) hasOpt(opt string) bool {
+	for _, o := range f.opts {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
 func fieldsToLine(level int, fields []do.Field) (string, map[string]string) {
 	var lines string
 	var keyCommentMap = make(map[string]string)
 	for _, field := range fields {
-		// 非导出
-		if !field.StructField.IsExported() {
+		df, ok := resolveDocField(field.StructField)
+		if !ok {
 			continue
 		}
 
 		var fieldName, fieldTypeName, fieldComment string
 
 		// 是否内嵌结构体
-		isEmbed := field.StructField.Anonymous
+		isEmbed := df.embed
 
 		// 字段名
-		fieldName = getFieldNameByTag(field.StructField.Tag)
-		if fieldName == "-" {
-			continue
-		}
-		if fieldName == "" {
-			fieldName = field.StructField.Name
-		}
+		fieldName = df.name
 
 		// 字段类型
 		fieldType := field.StructField.Type
@@ -859,50 +929,23 @@ func fieldsToLine(level int, fields []do.Field) (string, map[string]string) {
 			fieldType = fieldType.Elem()
 		}
 
-		parts := strings.Split(fieldName, ",")
-		if len(parts) > 1 {
-			// 名字肯定是第一个
-			fieldName = parts[0]
-
-			for _, part := range parts[1:] {
-				if part == "omitempty" {
-					continue
-				}
-				fieldTypeName = part
+		for _, opt := range df.opts {
+			if opt == "omitempty" {
+				continue
 			}
+			fieldTypeName = opt
 		}
 		if fieldTypeName == "" {
-			switch fieldType.Kind() {
-			case reflect.Struct, reflect.Interface:
-				fieldTypeName = "object"
-			case reflect.Slice:
-				sliceType := fieldType.Elem()
-				if sliceType.Kind() == reflect.Struct {
-					fieldTypeName = "object"
-				} else {
-					ft := replaceTypeName(sliceType)
-					if ft != "" {
-						fieldTypeName = ft
-					} else {
-						fieldTypeName = sliceType.String()
-					}
-				}
-				fieldTypeName += " list"
-			default:
-				ft := replaceTypeName(fieldType)
-				if ft != "" {
-					fieldTypeName = ft
-				} else {
-					fieldTypeName = fieldType.Kind().String()
-				}
-			}
+			fieldTypeName = typeNameOf(fieldType)
 		}
 
 		// 字段注释
 		fieldComment = field.Comment
 
 		key := "|" + fieldName
-		keyCommentMap[key] = fieldComment
+		if !isEmbed {
+			keyCommentMap[key] = fieldComment
+		}
 		ignoreKey := false
 
 		// 添加一行
@@ -935,6 +978,28 @@ func fieldsToLine(level int, fields []do.Field) (string, map[string]string) {
 	return lines, keyCommentMap
 }
 
+// typeNameOf 文档里字段的类型名
+func typeNameOf(fieldType reflect.Type) string {
+	switch fieldType.Kind() {
+	case reflect.Struct, reflect.Interface:
+		return "object"
+	case reflect.Slice:
+		sliceType := fieldType.Elem()
+		if sliceType.Kind() == reflect.Struct {
+			return "object list"
+		}
+		if ft := replaceTypeName(sliceType); ft != "" {
+			return ft + " list"
+		}
+		return sliceType.String() + " list"
+	default:
+		if ft := replaceTypeName(fieldType); ft != "" {
+			return ft
+		}
+		return fieldType.Kind().String()
+	}
+}
+
 func replaceTypeName(ft reflect.Type) (r string) {
 	if ft.PkgPath() == "github.com/donnol/do" &&
 		ft.Name() == "Id" {
@@ -964,17 +1029,22 @@ func dataToSummary(name string, data []byte, format string, isJSON bool, kcm map
 	)
 	var summary string
 
+	lang := "json"
+	if isJSON && format == "xml" {
+		lang = "xml"
+	}
 	summary += `<details>
-<summary>` + name + `</summary>` + "\n\n```json\n"
-	if isJSON {
+<summary>` + name + `</summary>` + "\n\n```" + lang + "\n"
+	if isJSON && format == "xml" {
 		var buf = new(bytes.Buffer)
 		if data != nil {
-			switch format {
-			case "xml":
-				XMLIndent(buf, data)
-			default:
-				JSONIndent(buf, data)
-			}
+			XMLIndent(buf, data)
+		}
+		summary += xmlWithComment(strings.TrimLeft(buf.String(), eol), kcm)
+	} else if isJSON {
+		var buf = new(bytes.Buffer)
+		if data != nil {
+			JSONIndent(buf, data)
 		}
 		// 逐行遍历
 		// 每遇到一个'{'表示开始一层，每遇到一个'}'表示结束一层
@@ -44,7 +44,7 @@ var (
 )
 
 func TestStructToBlock(t *testing.T) {
-	line, lkcm, err := structToBlock(paramName, http.MethodGet, &testtype.TestModel{})
+	line, lkcm, err := structToBlock(paramName, paramInQuery, "", &testtype.TestModel{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -77,7 +77,7 @@ func TestStructToBlock(t *testing.T) {
 
 	// struct slice
 	{
-		line, lkcm, err := structToBlock(paramName, http.MethodGet, &[]testtype.TestModel{
+		line, lkcm, err := structToBlock(paramName, paramInQuery, "", &[]testtype.TestModel{
 			{
 				Name: "abc",
 				List: []testtype.User{
@@ -2,7 +2,10 @@ package apitest
 
 import (
 	"bytes"
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"reflect"
 	"strings"
@@ -11,6 +14,7 @@ import (
 
 	"github.com/brianvoe/gofakeit/v6"
 	"github.com/donnol/apitest/testtype"
+	"github.com/gin-gonic/gin"
 )
 
 var (
@@ -219,24 +223,171 @@ func TestWriteFile(t *testing.T) {
 }
 
 func TestClientTimeout(t *testing.T) {
-	port := ":8800"
 	timeout := 1 * time.Second
 
-	go func() {
-		http.ListenAndServe(port, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			time.Sleep(timeout + time.Second)
-		}))
-	}()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(timeout + time.Second)
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	// without timeout
-	if err := NewAT("/", http.MethodGet, "timeout test", nil, nil).SetPort(port).Run().Err(); err != nil {
+	if err := NewAT("/", http.MethodGet, "timeout test", nil, nil).SetHost(u.Host).Run().Err(); err != nil {
 		t.Error(err)
 	}
 
 	// with timeout
-	if err := NewAT("/", http.MethodGet, "timeout test", nil, nil).SetPort(port).SetClientTimeout(timeout).Run().Err(); err != nil {
+	if err := NewAT("/", http.MethodGet, "timeout test", nil, nil).SetHost(u.Host).SetClientTimeout(timeout).Run().Err(); err != nil {
 		if !strings.Contains(err.Error(), "context deadline exceeded (Client.Timeout exceeded while awaiting headers)") {
 			t.Error(err)
 		}
 	}
 }
+
+func TestRunMethods(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Method", r.Method)
+		w.Header().Set("X-Query", r.URL.RawQuery)
+		w.Header().Set("X-Body", string(body))
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	param := &testtype.User{Id: 1, Name: "jd"}
+	for _, tc := range []struct {
+		method    string
+		setIn     func(at *AT) *AT
+		wantQuery string
+		wantBody  string
+		wantDoc   string
+	}{
+		{method: http.MethodPatch, wantBody: `{"id":"1","name":"jd","age":0,"addr":{"city":"","home":""},"phone":""}`, wantDoc: "Param - Body"},
+		{method: http.MethodHead, wantQuery: "addr=%7B+%7D&age=0&id=1&name=jd&phone=", wantDoc: "Param - Query"},
+		{method: http.MethodOptions, wantQuery: "addr=%7B+%7D&age=0&id=1&name=jd&phone=", wantDoc: "Param - Query"},
+		{method: http.MethodGet, setIn: (*AT).ParamInBody, wantBody: `{"id":"1","name":"jd","age":0,"addr":{"city":"","home":""},"phone":""}`, wantDoc: "Param - Body"},
+		{method: http.MethodPatch, setIn: (*AT).ParamInQuery, wantQuery: "addr=%7B+%7D&age=0&id=1&name=jd&phone=", wantDoc: "Param - Query"},
+	} {
+		t.Run(tc.method, func(t *testing.T) {
+			at := NewAT("/", tc.method, "method test", nil, nil).SetHost(u.Host).SetParam(param)
+			if tc.setIn != nil {
+				at = tc.setIn(at)
+			}
+			if err := at.Run().EqualCode(http.StatusOK).Err(); err != nil {
+				t.Fatal(err)
+			}
+			h := at.Resp().Header
+			if h.Get("X-Method") != tc.method {
+				t.Errorf("bad method: %s != %s", h.Get("X-Method"), tc.method)
+			}
+			if h.Get("X-Query") != tc.wantQuery {
+				t.Errorf("bad query: %s != %s", h.Get("X-Query"), tc.wantQuery)
+			}
+			if h.Get("X-Body") != tc.wantBody {
+				t.Errorf("bad body: %s != %s", h.Get("X-Body"), tc.wantBody)
+			}
+
+			doc := new(bytes.Buffer)
+			at = NewAT("/", tc.method, "method test", nil, nil).SetParam(param)
+			if tc.setIn != nil {
+				at = tc.setIn(at)
+			}
+			if err := at.FakeRun().Result(&testtype.User{}).WriteFile(doc).Err(); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(doc.String(), tc.wantDoc) {
+				t.Errorf("doc doesn't contain %q", tc.wantDoc)
+			}
+		})
+	}
+}
+
+func TestSetHandler(t *testing.T) {
+	engine := gin.New()
+	engine.POST("/user", func(c *gin.Context) {
+		var p testtype.User
+		if err := c.ShouldBindJSON(&p); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"msg": err.Error()})
+			return
+		}
+		p.Age = 18
+		c.JSON(http.StatusOK, p)
+	})
+
+	var r testtype.User
+	doc := new(bytes.Buffer)
+	if err := NewAT("/user", http.MethodPost, "添加用户信息", nil, nil).
+		SetHandler(engine).
+		SetParam(&testtype.User{Id: 1, Name: "jd"}).
+		Run().
+		EqualCode(http.StatusOK).
+		Result(&r).
+		Equal(r.Age, 18, r.Name, "jd").
+		WriteFile(doc).
+		Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(doc.String(), `"age": 18`) {
+		t.Fatalf("doc doesn't contain response: %s", doc.String())
+	}
+
+	if err := NewAT("/user/none", http.MethodGet, "not found", nil, nil).
+		SetHandler(engine).
+		Run().
+		EqualCode(http.StatusNotFound).
+		Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHeaderCookieAssert(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Add("Vary", "Origin")
+		w.Header().Add("Vary", "Accept-Encoding")
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", HttpOnly: true, Secure: true, SameSite: http.SameSiteStrictMode})
+		http.SetCookie(w, &http.Cookie{Name: "lang", Value: "zh"})
+		w.Write([]byte(`{}`))
+	})
+
+	at := NewAT("/", http.MethodGet, "header", nil, nil).SetHandler(handler).Run()
+	if err := at.
+		EqualHeader("Cache-Control", "no-cache").
+		HeaderContains("Vary", "Encoding").
+		EqualContentType("application/json").
+		EqualContentType("application/json; charset=UTF-8").
+		EqualCookie("session", "abc").
+		SetsCookie("session", CookieHttpOnly, CookieSecure, CookieSameSiteStrict).
+		SetsCookie("lang").
+		Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		f    func(at *AT) *AT
+		want string
+	}{
+		{"header", func(at *AT) *AT { return at.EqualHeader("Cache-Control", "max-age=60") }, `header "Cache-Control" not equal, have "no-cache", want "max-age=60"`},
+		{"contains", func(at *AT) *AT { return at.HeaderContains("Vary", "Cookie") }, `header "Vary" doesn't contain "Cookie"`},
+		{"content type", func(at *AT) *AT { return at.EqualContentType("application/xml") }, `content type not equal`},
+		{"cookie", func(at *AT) *AT { return at.EqualCookie("lang", "en") }, `cookie "lang" not equal, have "zh", want "en"`},
+		{"no cookie", func(at *AT) *AT { return at.SetsCookie("token") }, `cookie "token" not found`},
+		{"flag", func(at *AT) *AT { return at.SetsCookie("lang", CookieHttpOnly) }, `cookie "lang" doesn't have HttpOnly`},
+		{"first error wins", func(at *AT) *AT { return at.EqualCode(http.StatusNotFound).SetsCookie("token") }, `bad status code`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.f(NewAT("/", http.MethodGet, "header", nil, nil).SetHandler(handler).Run()).Err()
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("bad error: %v, want %s", err, tc.want)
+			}
+		})
+	}
+}
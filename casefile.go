@@ -0,0 +1,290 @@
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+type (
+	// CaseFile 用例文件，不写Go代码也可以维护用例和文档，支持yaml和json
+	CaseFile struct {
+		Host   string            `json:"host" yaml:"host"`     // 如：localhost:8080，为空时使用prepare里的设置
+		Header map[string]string `json:"header" yaml:"header"` // 所有用例共用的header
+		Cases  []*Case           `json:"cases" yaml:"cases"`
+	}
+
+	// Case 用例
+	Case struct {
+		Comment string            `json:"comment" yaml:"comment"`
+		Path    string            `json:"path" yaml:"path"`
+		Method  string            `json:"method" yaml:"method"`
+		Status  string            `json:"status" yaml:"status"` // 接口状态，如：implemented、已实现
+		Header  map[string]string `json:"header" yaml:"header"`
+		Auth    string            `json:"auth" yaml:"auth"`       // 认证header的键，值取自header
+		ParamIn string            `json:"paramIn" yaml:"paramIn"` // query或body，默认根据请求方法决定
+		Params  map[string]any    `json:"params" yaml:"params"`
+
+		Code      int            `json:"code" yaml:"code"`           // 期望的状态码
+		Equal     map[string]any `json:"equal" yaml:"equal"`         // 路径 -> 期望的值，如：data.name: jd
+		Exists    []string       `json:"exists" yaml:"exists"`       // 必须存在的路径
+		NotExists []string       `json:"notExists" yaml:"notExists"` // 必须不存在的路径
+		Len       map[string]int `json:"len" yaml:"len"`             // 路径 -> 期望的长度
+	}
+)
+
+// ReadCaseFile 读取用例文件，根据扩展名判断格式，.json之外的均按yaml处理
+func ReadCaseFile(file string) (*CaseFile, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	f := new(CaseFile)
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(f)
+	default:
+		err = yaml.Unmarshal(data, f)
+		for _, c := range f.Cases {
+			for k, v := range c.Params {
+				c.Params[k] = normalizeYAML(v)
+			}
+			for k, v := range c.Equal {
+				c.Equal[k] = normalizeYAML(v)
+			}
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode case file %s failed: %w", file, err)
+	}
+
+	for i, c := range f.Cases {
+		if c.Path == "" || c.Method == "" {
+			return nil, fmt.Errorf("case file %s no.%d: path and method are required", file, i+1)
+		}
+		c.Method = strings.ToUpper(c.Method)
+		if _, err := parseStatus(c.Status); err != nil {
+			return nil, fmt.Errorf("case file %s no.%d: %w", file, i+1, err)
+		}
+	}
+
+	return f, nil
+}
+
+// RunCaseFile 读取用例文件并将每个用例作为子测试执行，w不为nil时写入文档；prepare用于设置handler、client等
+func RunCaseFile(t *testing.T, file string, w io.Writer, prepare func(*AT) *AT) {
+	f, err := ReadCaseFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Run(t, w, prepare)
+}
+
+// Run 将每个用例作为子测试执行
+func (f *CaseFile) Run(t *testing.T, w io.Writer, prepare func(*AT) *AT) {
+	for _, c := range f.Cases {
+		c := c
+		t.Run(c.name(), func(t *testing.T) {
+			at := f.NewAT(c)
+			if prepare != nil {
+				at = prepare(at)
+			}
+			if err := c.run(at, w).Err(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// NewAT 根据用例新建AT，还未执行
+func (f *CaseFile) NewAT(c *Case) *AT {
+	header := make(http.Header)
+	for k, v := range f.Header {
+		header.Set(k, v)
+	}
+	for k, v := range c.Header {
+		header.Set(k, v)
+	}
+
+	at := NewAT(c.Path, c.Method, c.Comment, header, nil)
+	if f.Host != "" {
+		at.SetHost(f.Host)
+	}
+	if c.Auth != "" {
+		at.MarkAuthHeader(c.Auth, header.Get(c.Auth))
+	}
+	status, _ := parseStatus(c.Status)
+	at.SetStatus(status)
+
+	switch {
+	case strings.EqualFold(c.ParamIn, paramInQuery):
+		at.ParamInQuery()
+	case strings.EqualFold(c.ParamIn, paramInBody):
+		at.ParamInBody()
+	}
+	if len(c.Params) > 0 {
+		at.SetParam(dynamicStruct(c.Params).Addr().Interface())
+	}
+
+	return at
+}
+
+func (c *Case) name() string {
+	if c.Comment != "" {
+		return c.Comment
+	}
+	return c.Method + " " + c.Path
+}
+
+// run 执行并校验，最后使用响应生成结果类型，用于文档
+func (c *Case) run(at *AT, w io.Writer) *AT {
+	at.Run()
+	if c.Code != 0 {
+		at.EqualCode(c.Code)
+	}
+	for _, path := range sortedKeys(c.Equal) {
+		at.EqualPath(path, c.Equal[path])
+	}
+	for _, path := range c.Exists {
+		at.ExistsPath(path)
+	}
+	for _, path := range c.NotExists {
+		at.NotExistsPath(path)
+	}
+	lens := make([]string, 0, len(c.Len))
+	for path := range c.Len {
+		lens = append(lens, path)
+	}
+	sort.Strings(lens)
+	for _, path := range lens {
+		at.LenPath(path, c.Len[path])
+	}
+	if at.Err() != nil || w == nil {
+		return at
+	}
+
+	var result reflect.Value
+	data, _, err := copyResponseBody(at.resp)
+	if err != nil {
+		return at.setErr(err)
+	}
+	var v any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err == nil {
+		result = dynamicStruct(v)
+	}
+	if !result.IsValid() || result.Kind() != reflect.Struct {
+		result = reflect.ValueOf(struct{}{}) // 响应不是json对象时，文档里不列出返回字段
+	}
+	r := reflect.New(result.Type())
+	if result.Type().NumField() > 0 {
+		at.Result(r.Interface())
+	} else {
+		at.result = r.Interface()
+	}
+
+	return at.WriteFile(w)
+}
+
+// parseStatus 支持常量名（不区分大小写）和中文名
+func parseStatus(s string) (Status, error) {
+	if s == "" {
+		return StatusNone, nil
+	}
+	names := map[string]Status{
+		"indesign":       StatusInDesign,
+		"notimplemented": StatusNotImplemented,
+		"implementation": StatusImplementation,
+		"implemented":    StatusImplemented,
+	}
+	if status, ok := names[strings.ToLower(s)]; ok {
+		return status, nil
+	}
+	for status := StatusInDesign; status <= StatusImplemented; status++ {
+		if status.String() == s {
+			return status, nil
+		}
+	}
+	return StatusNone, fmt.Errorf("unknown status %q", s)
+}
+
+// dynamicStruct 将解析得到的值转为结构体，对象的键作为json tag，以便像Go代码里的参数和结果一样生成文档
+func dynamicStruct(v any) reflect.Value {
+	switch vv := v.(type) {
+	case map[string]any:
+		keys := sortedKeys(vv)
+		fields := make([]reflect.StructField, 0, len(keys))
+		values := make([]reflect.Value, 0, len(keys))
+		used := make(map[string]bool)
+		for _, key := range keys {
+			name := goName(key)
+			if !token.IsExported(name) {
+				name = "X" + name
+			}
+			base := name
+			for i := 2; used[name]; i++ {
+				name = fmt.Sprintf("%s%d", base, i)
+			}
+			used[name] = true
+
+			value := dynamicStruct(vv[key])
+			fields = append(fields, reflect.StructField{
+				Name: name,
+				Type: value.Type(),
+				Tag:  reflect.StructTag(fmt.Sprintf(`json:%q`, key)),
+			})
+			values = append(values, value)
+		}
+		s := reflect.New(reflect.StructOf(fields)).Elem()
+		for i, value := range values {
+			s.Field(i).Set(value)
+		}
+		return s
+	case []any:
+		items := make([]reflect.Value, 0, len(vv))
+		var typ reflect.Type
+		for _, item := range vv {
+			value := dynamicStruct(item)
+			if typ == nil {
+				typ = value.Type()
+			} else if typ != value.Type() {
+				typ = reflect.TypeOf((*any)(nil)).Elem() // 元素类型不一致
+			}
+			items = append(items, value)
+		}
+		if typ == nil {
+			typ = reflect.TypeOf("")
+		}
+		s := reflect.MakeSlice(reflect.SliceOf(typ), 0, len(items))
+		for _, item := range items {
+			s = reflect.Append(s, item)
+		}
+		return s
+	case json.Number:
+		if i, err := vv.Int64(); err == nil {
+			return reflect.ValueOf(i)
+		}
+		if f, err := vv.Float64(); err == nil {
+			return reflect.ValueOf(f)
+		}
+		return reflect.ValueOf(vv.String())
+	case nil:
+		return reflect.ValueOf((*string)(nil))
+	}
+	return reflect.ValueOf(v)
+}
@@ -0,0 +1,56 @@
+package apitest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRunCaseFile(t *testing.T) {
+	mux := scenarioMux()
+	prepare := func(at *AT) *AT {
+		return at.SetHandler(mux)
+	}
+
+	buf := new(bytes.Buffer)
+	RunCaseFile(t, "testdata/cases/book.yaml", buf, prepare)
+	doc := buf.String()
+	for _, want := range []string{
+		"## " + toAnchor("新建书本 [已实现]"),
+		"## " + toAnchor("获取书本 [实现中]"),
+		"Param - Query",
+		"- Authorization: abc",
+		"* title (*string*)",
+	} {
+		if !strings.Contains(doc, want) {
+			t.Fatalf("doc should contain %q, have:\n%s", want, doc)
+		}
+	}
+
+	RunCaseFile(t, "testdata/cases/login.json", nil, prepare)
+}
+
+func TestReadCaseFile(t *testing.T) {
+	f, err := ReadCaseFile("testdata/cases/book.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Cases) != 2 || f.Cases[0].Method != "POST" {
+		t.Fatalf("bad cases: %+v", f.Cases)
+	}
+
+	at := f.NewAT(f.Cases[1])
+	if err := at.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if at.paramPosition() != paramInQuery || at.status != StatusImplementation {
+		t.Fatalf("bad at: %s %v", at.paramPosition(), at.status)
+	}
+	if curl := at.Curl(); !strings.Contains(curl, "/book?id=7") {
+		t.Fatalf("bad curl: %s", curl)
+	}
+
+	if _, err := parseStatus("done"); err == nil {
+		t.Fatal("want error for unknown status")
+	}
+}
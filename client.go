@@ -0,0 +1,137 @@
+package apitest
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	defaultClientTimeout = 10 * time.Second
+)
+
+// clientKey 决定client的配置项，配置相同的AT共享同一个client
+type clientKey struct {
+	timeout            time.Duration
+	https              bool
+	insecureSkipVerify bool
+	caCertPath         string
+	certFile           string
+	keyFile            string
+}
+
+var (
+	clientMu sync.Mutex
+	clients  = make(map[clientKey]*http.Client)
+
+	// transports 以TLS配置区分，不同超时时间的client可以共用同一个transport
+	transports = make(map[clientKey]*http.Transport)
+)
+
+// getClient 获取client：优先使用SetClient和SetTransport设置的，否则使用包级别共享的client
+func (at *AT) getClient() (*http.Client, error) {
+	timeout := defaultClientTimeout
+	if at.clientTimeout != 0 {
+		timeout = at.clientTimeout
+	}
+
+	if at.client != nil {
+		if at.clientTimeout == 0 {
+			return at.client, nil
+		}
+		client := *at.client
+		client.Timeout = timeout
+		return &client, nil
+	}
+
+	if at.transport != nil {
+		return &http.Client{
+			Timeout:   timeout,
+			Transport: at.transport,
+		}, nil
+	}
+
+	key := clientKey{
+		timeout: timeout,
+		https:   at.scheme == "https",
+	}
+	if key.https {
+		key.insecureSkipVerify = at.insecureSkipVerify
+		if !key.insecureSkipVerify {
+			key.caCertPath = at.caCertPath
+			key.certFile = at.certFile
+			key.keyFile = at.keyFile
+		}
+	}
+
+	clientMu.Lock()
+	defer clientMu.Unlock()
+
+	if client, ok := clients[key]; ok {
+		return client, nil
+	}
+
+	tkey := key
+	tkey.timeout = 0
+	transport, ok := transports[tkey]
+	if !ok {
+		var err error
+		transport, err = newTransport(tkey)
+		if err != nil {
+			return nil, err
+		}
+		transports[tkey] = transport
+	}
+
+	client := &http.Client{
+		Timeout:   timeout, // 超时
+		Transport: transport,
+	}
+	clients[key] = client
+
+	return client, nil
+}
+
+func newTransport(key clientKey) (*http.Transport, error) {
+	var tlsConfig *tls.Config
+	if key.https {
+		if key.insecureSkipVerify {
+			tlsConfig = &tls.Config{
+				InsecureSkipVerify: key.insecureSkipVerify,
+			}
+		} else {
+			caCrt, err := os.ReadFile(key.caCertPath)
+			if err != nil {
+				return nil, err
+			}
+
+			pool := x509.NewCertPool()
+			pool.AppendCertsFromPEM(caCrt)
+
+			cliCrt, err := tls.LoadX509KeyPair(key.certFile, key.keyFile)
+			if err != nil {
+				return nil, err
+			}
+
+			tlsConfig = &tls.Config{
+				RootCAs:      pool,
+				Certificates: []tls.Certificate{cliCrt},
+			}
+		}
+	}
+
+	// https://medium.com/@nate510/don-t-use-go-s-default-http-client-4804cb19f779
+	return &http.Transport{
+		Dial: (&net.Dialer{
+			Timeout: 5 * time.Second,
+		}).Dial,
+		TLSHandshakeTimeout: 5 * time.Second,
+		MaxIdleConns:        100, // 最大空闲连接数
+		MaxIdleConnsPerHost: 100, // 每个域名最大空闲连接数
+		TLSClientConfig:     tlsConfig,
+	}, nil
+}
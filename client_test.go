@@ -0,0 +1,107 @@
+package apitest
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSharedClient(t *testing.T) {
+	var conns int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":0}`))
+	}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&conns, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := NewAT("/", http.MethodGet, "shared client", nil, nil).
+			SetHost(u.Host).
+			Run().
+			EqualCode(http.StatusOK).
+			Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt64(&conns); n != 1 {
+		t.Fatalf("bad connection number: %d != 1", n)
+	}
+
+	c1, err := NewAT("/", http.MethodGet, "", nil, nil).getClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := NewAT("/", http.MethodGet, "", nil, nil).getClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c1 != c2 {
+		t.Fatalf("client is not shared")
+	}
+	c3, err := NewAT("/", http.MethodGet, "", nil, nil).SetClientTimeout(time.Second).getClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c3 == c1 || c3.Timeout != time.Second || c3.Transport != c1.Transport {
+		t.Fatalf("bad client with timeout: %+v", c3)
+	}
+}
+
+type countTransport struct {
+	n int64
+}
+
+func (t *countTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt64(&t.n, 1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestSetClientAndTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transport := &countTransport{}
+	if err := NewAT("/", http.MethodGet, "transport", nil, nil).
+		SetHost(u.Host).
+		SetTransport(transport).
+		Run().
+		EqualCode(http.StatusOK).
+		Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &http.Client{Transport: transport}
+	if err := NewAT("/", http.MethodGet, "client", nil, nil).
+		SetHost(u.Host).
+		SetClient(client).
+		SetClientTimeout(time.Second).
+		Run().
+		EqualCode(http.StatusOK).
+		Err(); err != nil {
+		t.Fatal(err)
+	}
+	if client.Timeout != 0 {
+		t.Fatalf("client should not be modified")
+	}
+
+	if n := atomic.LoadInt64(&transport.n); n != 2 {
+		t.Fatalf("bad round trip number: %d != 2", n)
+	}
+}
@@ -0,0 +1,50 @@
+// openapi2test 根据OpenAPI 3文档生成使用apitest的测试代码
+//
+// 用法：
+//
+//	openapi2test -in openapi.yaml -out api_test.go -pkg api
+package main
+
+import (
+	"bytes"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/donnol/apitest"
+)
+
+func main() {
+	var (
+		in  = flag.String("in", "", "OpenAPI文档，支持json和yaml格式")
+		out = flag.String("out", "", "生成的测试文件，为空时输出到标准输出")
+		pkg = flag.String("pkg", "api", "测试文件的包名")
+	)
+	flag.Parse()
+
+	if *in == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	doc, err := apitest.ReadOpenAPI(*in)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := apitest.GenTestFromOpenAPI(doc, *pkg, buf); err != nil {
+		log.Fatal(err)
+	}
+
+	if *out == "" {
+		if _, err := buf.WriteTo(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if err := os.WriteFile(*out, buf.Bytes(), 0o644); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Write test file %s\n", *out)
+}
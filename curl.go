@@ -0,0 +1,85 @@
+package apitest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Curl 将请求转为可以直接复制执行的curl命令；还没执行时，根据参数构造请求
+func (at *AT) Curl() string {
+	req, reqBody := at.req, at.reqBody
+	if req == nil {
+		ex := at.newExchange()
+		if err := at.prepare(ex); err != nil {
+			at.setErr(err)
+			return ""
+		}
+		req, reqBody = ex.req, ex.reqBody
+	}
+
+	parts := []string{"curl"}
+	switch req.Method {
+	case http.MethodGet:
+		// 带有请求体时curl默认使用POST
+		if len(reqBody) > 0 || at.file != "" || at.multipart != nil {
+			parts = append(parts, "-X", req.Method)
+		}
+	case http.MethodHead:
+		parts = append(parts, "--head")
+	default:
+		parts = append(parts, "-X", req.Method)
+	}
+	parts = append(parts, shellQuote(req.URL.String()))
+
+	keys := make([]string, 0, len(req.Header))
+	for key := range req.Header {
+		// 上传文件时由curl生成带boundary的Content-Type
+		if (at.file != "" || at.multipart != nil) && key == "Content-Type" {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range req.Header[key] {
+			parts = append(parts, "-H", shellQuote(key+": "+value))
+		}
+	}
+
+	switch {
+	case at.file != "":
+		parts = append(parts, "-F", shellQuote("file=@"+at.file))
+	case at.multipart != nil:
+		args, err := at.multipart.curlArgs(at.param)
+		if err != nil {
+			at.setErr(err)
+			return ""
+		}
+		parts = append(parts, args...)
+	case len(reqBody) > 0 && !utf8.Valid(reqBody):
+		parts = append(parts, "--data-binary", ansiQuote(reqBody))
+	case len(reqBody) > 0:
+		parts = append(parts, "--data-raw", shellQuote(string(reqBody)))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// ansiQuote 二进制内容使用$'\xNN'的形式
+func ansiQuote(data []byte) string {
+	var b strings.Builder
+	b.WriteString("$'")
+	for _, c := range data {
+		fmt.Fprintf(&b, "\\x%02x", c)
+	}
+	b.WriteString("'")
+	return b.String()
+}
+
+// shellQuote 使用单引号包裹，内部的单引号先结束引号再转义
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,126 @@
+package apitest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestCurl(t *testing.T) {
+	at := NewAT("/user?from=test", http.MethodPost, "新建用户", http.Header{"X-Request-Id": {"1"}}, []*http.Cookie{{Name: "session", Value: "abc"}}).
+		SetParam(map[string]any{"name": "j'd"}).
+		FakeRun()
+	want := `curl -X POST 'http://localhost:80/user?from=test' -H 'Content-Type: application/json; charset=utf-8' -H 'Cookie: session=abc' -H 'X-Request-Id: 1' --data-raw '{"name":"j'\''d"}'`
+	if got := at.Curl(); got != want {
+		t.Fatalf("bad curl, have %s, want %s", got, want)
+	}
+
+	// 上传文件
+	at = NewAT("/upload", http.MethodPost, "上传", nil, nil).
+		SetParam(map[string]any{}).
+		SetFile("testdata/user.schema.json").
+		FakeRun()
+	want = `curl -X POST 'http://localhost:80/upload' -F 'file=@testdata/user.schema.json'`
+	if got := at.Curl(); got != want {
+		t.Fatalf("bad curl, have %s, want %s", got, want)
+	}
+
+	// 还没执行时根据参数构造
+	at = NewAT("/user", http.MethodGet, "获取用户", nil, nil).SetParam(&bookParam{Id: 1})
+	want = `curl 'http://localhost:80/user?id=1' -H 'Content-Type: application/json; charset=utf-8'`
+	if got := at.Curl(); got != want {
+		t.Fatalf("bad curl, have %s, want %s", got, want)
+	}
+}
+
+func TestCurlReplay(t *testing.T) {
+	if _, err := exec.LookPath("curl"); err != nil {
+		t.Skip("curl not found")
+	}
+
+	// 服务端在另外的goroutine里收到请求
+	got := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		cookie, _ := r.Cookie("session")
+		got <- r.Method + " " + r.URL.String() + " " + cookie.Value + " " + string(body)
+	}))
+	defer server.Close()
+
+	for _, at := range []*AT{
+		NewAT("/user", http.MethodPut, "修改用户", nil, []*http.Cookie{{Name: "session", Value: "abc"}}).
+			SetParam(map[string]any{"name": "j'd"}),
+		// 参数在请求体里的GET请求不能被curl当成POST
+		NewAT("/user", http.MethodGet, "查询用户", nil, []*http.Cookie{{Name: "session", Value: "abc"}}).
+			ParamInBody().
+			SetParam(map[string]any{"name": "jd"}),
+	} {
+		at.SetHost(strings.TrimPrefix(server.URL, "http://")).Run()
+		if err := at.Err(); err != nil {
+			t.Fatal(err)
+		}
+		want := <-got
+
+		cmd := exec.Command("sh", "-c", at.Curl()+" -sS")
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+		if have := <-got; have != want {
+			t.Fatalf("bad replay, have %s, want %s", have, want)
+		}
+	}
+}
+
+func TestCurlMultipartFormString(t *testing.T) {
+	server := httptest.NewServer(multipartMux())
+	defer server.Close()
+
+	at := NewAT("/import", http.MethodPost, "导入用户", nil, nil).
+		SetHost(strings.TrimPrefix(server.URL, "http://")).
+		SetMultipart(NewMultipart().
+			Field("remark", "@testdata/upload/users.csv").
+			Field("note", "<testdata/upload/users.csv").
+			File("users", "testdata/upload/users.csv", "")).
+		Run()
+	if err := at.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	curl := at.Curl()
+	for _, want := range []string{
+		"--form-string 'remark=@testdata/upload/users.csv'",
+		"--form-string 'note=<testdata/upload/users.csv'",
+		"-F 'users=@testdata/upload/users.csv;type=text/csv",
+	} {
+		if !strings.Contains(curl, want) {
+			t.Fatalf("curl should contain %q, have %s", want, curl)
+		}
+	}
+
+	if _, err := exec.LookPath("curl"); err != nil {
+		t.Skip("curl not found")
+	}
+	out, err := exec.Command("sh", "-c", curl+" -sS").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r importResult
+	if err := json.Unmarshal(out, &r); err != nil {
+		t.Fatalf("bad output %s: %v", out, err)
+	}
+	if got := r.Fields["remark"]; len(got) != 1 || got[0] != "@testdata/upload/users.csv" {
+		t.Fatalf("bad remark: %v", got)
+	}
+	if got := r.Fields["note"]; len(got) != 1 || got[0] != "<testdata/upload/users.csv" {
+		t.Fatalf("bad note: %v", got)
+	}
+	if r.Files["users"] == nil || r.Files["remark"] != nil {
+		t.Fatalf("bad files: %+v", r.Files)
+	}
+}
@@ -0,0 +1,141 @@
+package apitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// diffTree 逐个字段比较两棵由decodeTree得到的树，返回每个不同之处，如：data.name: have "jc", want "jd"
+func diffTree(path string, have, want any) []string {
+	var diffs []string
+	diffTreeTo(&diffs, path, have, want)
+	return diffs
+}
+
+func diffTreeTo(diffs *[]string, path string, have, want any) {
+	switch wv := want.(type) {
+	case map[string]any:
+		hv, ok := have.(map[string]any)
+		if !ok {
+			break
+		}
+		for _, k := range sortedKeys(wv) {
+			sub := joinPath(path, k)
+			h, ok := hv[k]
+			if !ok {
+				*diffs = append(*diffs, fmt.Sprintf("%s: missing, want %s", sub, fragment(wv[k])))
+				continue
+			}
+			diffTreeTo(diffs, sub, h, wv[k])
+		}
+		for _, k := range sortedKeys(hv) {
+			if _, ok := wv[k]; !ok {
+				*diffs = append(*diffs, fmt.Sprintf("%s: unexpected, have %s", joinPath(path, k), fragment(hv[k])))
+			}
+		}
+		return
+	case []any:
+		hv, ok := have.([]any)
+		if !ok {
+			break
+		}
+		if len(hv) != len(wv) {
+			*diffs = append(*diffs, fmt.Sprintf("%s: length %d, want %d", displayPath(path), len(hv), len(wv)))
+		}
+		for i := 0; i < len(hv) || i < len(wv); i++ {
+			sub := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(hv):
+				*diffs = append(*diffs, fmt.Sprintf("%s: missing, want %s", sub, fragment(wv[i])))
+			case i >= len(wv):
+				*diffs = append(*diffs, fmt.Sprintf("%s: unexpected, have %s", sub, fragment(hv[i])))
+			default:
+				diffTreeTo(diffs, sub, hv[i], wv[i])
+			}
+		}
+		return
+	}
+
+	if !equalTree(have, want) {
+		*diffs = append(*diffs, fmt.Sprintf("%s: have %s, want %s", displayPath(path), fragment(have), fragment(want)))
+	}
+}
+
+// joinPath 拼接路径，键里有'.'等特殊字符时使用["key"]的形式
+func joinPath(path, key string) string {
+	if strings.ContainsAny(key, `.[]"' `) || key == "" {
+		data, _ := json.Marshal(key)
+		return path + "[" + string(data) + "]"
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "$"
+	}
+	return path
+}
+
+// removePath 从树里删除路径对应的值，支持使用[*]匹配列表的所有元素
+func removePath(root any, path string) error {
+	tokens, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+	removeTokens(root, tokens)
+	return nil
+}
+
+func removeTokens(cur any, tokens []pathToken) {
+	token, last := tokens[0], len(tokens) == 1
+	if token.isKey {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return
+		}
+		if last {
+			delete(m, token.key)
+			return
+		}
+		if v, ok := m[token.key]; ok {
+			removeTokens(v, tokens[1:])
+		}
+		return
+	}
+
+	list, ok := cur.([]any)
+	if !ok {
+		return
+	}
+	// 列表元素置为nil而不是删除，以免后面元素的下标发生变化
+	if token.wildcard {
+		for i := range list {
+			if last {
+				list[i] = nil
+				continue
+			}
+			removeTokens(list[i], tokens[1:])
+		}
+		return
+	}
+	index := token.index
+	if index < 0 {
+		index += len(list)
+	}
+	if index < 0 || index >= len(list) {
+		return
+	}
+	if last {
+		list[index] = nil
+		return
+	}
+	removeTokens(list[index], tokens[1:])
+}
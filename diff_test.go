@@ -0,0 +1,46 @@
+package apitest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffTree(t *testing.T) {
+	decode := func(s string) any {
+		v, err := decodeTree("json", []byte(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	for _, tc := range []struct {
+		name       string
+		have, want string
+		ignores    []string
+		diffs      []string
+	}{
+		{"equal", `{"a":1,"b":[1,2]}`, `{"b":[1,2.0],"a":1}`, nil, nil},
+		{"value", `{"a":{"b.c":1}}`, `{"a":{"b.c":2}}`, nil, []string{`a["b.c"]: have 1, want 2`}},
+		{"type", `{"a":"1"}`, `{"a":1}`, nil, []string{`a: have "1", want 1`}},
+		{"root", `[1]`, `{"a":1}`, nil, []string{`$: have [1], want {"a":1}`}},
+		{"list", `[1,2,3]`, `[1,3]`, nil, []string{"$: length 3, want 2", "[1]: have 2, want 3", "[2]: unexpected, have 3"}},
+		{"ignore", `{"a":1,"t":1,"l":[{"x":1,"y":1},{"x":2,"y":1}]}`, `{"a":1,"t":2,"l":[{"x":1,"y":2},{"x":2,"y":2}]}`, []string{"t", "l[*].y"}, nil},
+		{"ignore index", `[1,2,3]`, `[1,4,3]`, []string{"[1]"}, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			have, want := decode(tc.have), decode(tc.want)
+			for _, path := range tc.ignores {
+				if err := removePath(have, path); err != nil {
+					t.Fatal(err)
+				}
+				if err := removePath(want, path); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if diffs := diffTree("", have, want); !reflect.DeepEqual(diffs, tc.diffs) {
+				t.Fatalf("bad diffs, have %q, want %q", diffs, tc.diffs)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+type encoderParam struct {
+	Name string `json:"name" yaml:"name"`
+	Age  int    `json:"age" yaml:"age"`
+}
+
+func TestRegisterParamEncoder(t *testing.T) {
+	const format = "yaml-test"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/yaml" {
+			http.Error(w, "bad content type", http.StatusBadRequest)
+			return
+		}
+		var p encoderParam
+		data, _ := io.ReadAll(r.Body)
+		if err := yaml.Unmarshal(data, &p); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(p)
+	})
+
+	var r encoderParam
+	at := NewAT("/user", http.MethodPost, "新建用户", nil, nil).
+		RegisterParamEncoder(format, func(param any) ([]byte, error) {
+			return yaml.Marshal(param)
+		}, "application/yaml").
+		RegisterParamFormatter(format, func(data []byte) ([]byte, error) {
+			var p encoderParam
+			if err := yaml.Unmarshal(data, &p); err != nil {
+				return nil, err
+			}
+			return json.Marshal(map[string]any{"name": p.Name, "age": p.Age, "from": "yaml"})
+		}).
+		SetHandler(mux).
+		SetParamFormat(format).
+		SetParam(&encoderParam{Name: "jd", Age: 18}).
+		Run().
+		EqualCode(http.StatusOK).
+		Result(&r)
+	if err := at.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if r.Name != "jd" || r.Age != 18 {
+		t.Fatalf("bad result: %+v", r)
+	}
+
+	if curl := at.Curl(); !strings.Contains(curl, "-H 'Content-Type: application/yaml'") || !strings.Contains(curl, "--data-raw 'name: jd\nage: 18\n'") {
+		t.Fatalf("bad curl: %s", curl)
+	}
+	item, err := at.postmanItem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Request.Body.Options.Raw.Language != "text" {
+		t.Fatalf("bad postman language: %s", item.Request.Body.Options.Raw.Language)
+	}
+	if ct := contentTypeOf(format); ct != "application/yaml" {
+		t.Fatalf("bad content type: %s", ct)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := at.WriteFile(buf).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if doc := buf.String(); !strings.Contains(doc, `"from": "yaml"`) || !strings.Contains(doc, "- Content-Type: application/yaml") {
+		t.Fatalf("bad doc:\n%s", doc)
+	}
+}
+
+func TestParamEncoderBinary(t *testing.T) {
+	at := NewAT("/user", http.MethodPost, "新建用户", nil, nil).
+		RegisterParamEncoder("binary-test", func(param any) ([]byte, error) {
+			return []byte{0x0a, 0xff, '\''}, nil
+		}, "application/octet-stream").
+		SetParamFormat("binary-test").
+		SetParam(&encoderParam{Name: "jd"})
+	if curl := at.Curl(); !strings.HasSuffix(curl, `--data-binary $'\x0a\xff\x27'`) {
+		t.Fatalf("bad curl: %s", curl)
+	}
+
+	at = NewAT("/user", http.MethodPost, "新建用户", nil, nil).
+		SetParamFormat("unknown-test").
+		SetParam(&encoderParam{Name: "jd"})
+	at.Curl()
+	if err := at.Err(); err == nil || !strings.Contains(err.Error(), `unknown param format "unknown-test"`) {
+		t.Fatalf("bad error: %v", err)
+	}
+}
@@ -0,0 +1,289 @@
+package apitest
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxEqualDiffs = 50 // 最多列出的不同之处
+)
+
+type (
+	// EqualOption Equal的比较选项
+	EqualOption func(*equalConfig)
+
+	equalConfig struct {
+		ignores        []*regexp.Regexp
+		nilEqualsEmpty bool
+		floatTolerance float64
+		timeTolerance  time.Duration
+	}
+)
+
+// IgnoreFields 忽略字段：以'.'开头时匹配完整路径，如：".AddressList[*].Position"；否则匹配任意层级的字段，如："CreatedAt"、"Model.Id"
+func IgnoreFields(paths ...string) EqualOption {
+	return func(c *equalConfig) {
+		for _, path := range paths {
+			p := regexp.QuoteMeta(path)
+			p = strings.ReplaceAll(p, regexp.QuoteMeta("[*]"), `\[[^\]]*\]`)
+			if strings.HasPrefix(path, ".") {
+				p = "^" + p + "$"
+			} else {
+				p = `(^|\.)` + p + "$"
+			}
+			c.ignores = append(c.ignores, regexp.MustCompile(p))
+		}
+	}
+}
+
+// NilEqualsEmpty nil与空切片、空map视为相等
+func NilEqualsEmpty() EqualOption {
+	return func(c *equalConfig) {
+		c.nilEqualsEmpty = true
+	}
+}
+
+// FloatTolerance 浮点数相差不超过tolerance时视为相等
+func FloatTolerance(tolerance float64) EqualOption {
+	return func(c *equalConfig) {
+		c.floatTolerance = tolerance
+	}
+}
+
+// TimeTolerance 时间相差不超过tolerance时视为相等
+func TimeTolerance(tolerance time.Duration) EqualOption {
+	return func(c *equalConfig) {
+		c.timeTolerance = tolerance
+	}
+}
+
+// EqualOptions 设置之后Equal和EqualThen使用的比较选项
+func (at *AT) EqualOptions(opts ...EqualOption) *AT {
+	at.equalOpts = append(at.equalOpts, opts...)
+	return at
+}
+
+// diffValue 逐个字段比较，返回每个不同之处，如：.AddressList[0].Position: "a" != "b"
+func diffValue(have, want any, opts ...EqualOption) []string {
+	c := &equalConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	d := &valueDiffer{config: c, visited: make(map[visit]bool)}
+	d.diff("", reflect.ValueOf(have), reflect.ValueOf(want))
+	return d.diffs
+}
+
+type visit struct {
+	have, want uintptr
+	typ        reflect.Type
+}
+
+type valueDiffer struct {
+	config  *equalConfig
+	visited map[visit]bool
+	diffs   []string
+}
+
+func (d *valueDiffer) add(path string, format string, args ...any) {
+	if path == "" {
+		path = "$"
+	}
+	d.diffs = append(d.diffs, path+": "+fmt.Sprintf(format, args...))
+}
+
+func (d *valueDiffer) ignored(path string) bool {
+	for _, re := range d.config.ignores {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *valueDiffer) diff(path string, have, want reflect.Value) {
+	if path != "" && d.ignored(path) {
+		return
+	}
+
+	if !have.IsValid() || !want.IsValid() {
+		if have.IsValid() != want.IsValid() {
+			d.add(path, "%s != %s", formatValue(have), formatValue(want))
+		}
+		return
+	}
+	if have.Type() != want.Type() {
+		d.add(path, "%s(%s) != %s(%s)", have.Type(), formatValue(have), want.Type(), formatValue(want))
+		return
+	}
+
+	if have.Type() == timeType && have.CanInterface() {
+		ht, wt := have.Interface().(time.Time), want.Interface().(time.Time)
+		delta := ht.Sub(wt)
+		if delta < 0 {
+			delta = -delta
+		}
+		if d.config.timeTolerance > 0 && delta <= d.config.timeTolerance {
+			return
+		}
+		if !reflect.DeepEqual(ht, wt) {
+			d.add(path, "%s != %s", formatValue(have), formatValue(want))
+		}
+		return
+	}
+
+	switch have.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		if have.IsNil() || want.IsNil() {
+			if have.IsNil() && want.IsNil() {
+				return
+			}
+			if d.config.nilEqualsEmpty && have.Kind() != reflect.Pointer && have.Len() == 0 && want.Len() == 0 {
+				return
+			}
+			d.add(path, "%s != %s", formatValue(have), formatValue(want))
+			return
+		}
+		// 循环引用
+		if have.Kind() != reflect.Slice || have.Len() > 0 {
+			v := visit{have.Pointer(), want.Pointer(), have.Type()}
+			if d.visited[v] {
+				return
+			}
+			d.visited[v] = true
+		}
+	}
+
+	switch have.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if have.Kind() == reflect.Interface && (have.IsNil() || want.IsNil()) {
+			if have.IsNil() != want.IsNil() {
+				d.add(path, "%s != %s", formatValue(have), formatValue(want))
+			}
+			return
+		}
+		d.diff(path, have.Elem(), want.Elem())
+	case reflect.Struct:
+		for i := 0; i < have.NumField(); i++ {
+			d.diff(path+"."+have.Type().Field(i).Name, have.Field(i), want.Field(i))
+		}
+	case reflect.Slice, reflect.Array:
+		if have.Len() != want.Len() {
+			d.add(path, "length %d != %d", have.Len(), want.Len())
+		}
+		for i := 0; i < have.Len() || i < want.Len(); i++ {
+			sub := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= have.Len():
+				if !d.ignored(sub) {
+					d.add(sub, "missing != %s", formatValue(want.Index(i)))
+				}
+			case i >= want.Len():
+				if !d.ignored(sub) {
+					d.add(sub, "%s != missing", formatValue(have.Index(i)))
+				}
+			default:
+				d.diff(sub, have.Index(i), want.Index(i))
+			}
+		}
+	case reflect.Map:
+		keys := append(have.MapKeys(), want.MapKeys()...)
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+		})
+		seen := make(map[string]bool)
+		for _, key := range keys {
+			sub := path + "[" + formatValue(key) + "]"
+			if seen[sub] {
+				continue
+			}
+			seen[sub] = true
+
+			hv, wv := have.MapIndex(key), want.MapIndex(key)
+			switch {
+			case !hv.IsValid():
+				if !d.ignored(sub) {
+					d.add(sub, "missing != %s", formatValue(wv))
+				}
+			case !wv.IsValid():
+				if !d.ignored(sub) {
+					d.add(sub, "%s != missing", formatValue(hv))
+				}
+			default:
+				d.diff(sub, hv, wv)
+			}
+		}
+	case reflect.Float32, reflect.Float64:
+		hf, wf := have.Float(), want.Float()
+		if hf != wf && !(d.config.floatTolerance > 0 && math.Abs(hf-wf) <= d.config.floatTolerance) {
+			d.add(path, "%s != %s", formatValue(have), formatValue(want))
+		}
+	case reflect.Func:
+		if !have.IsNil() || !want.IsNil() { // 与reflect.DeepEqual一致，函数只有都为nil时相等
+			d.add(path, "%s != %s", formatValue(have), formatValue(want))
+		}
+	default:
+		if !basicEqual(have, want) {
+			d.add(path, "%s != %s", formatValue(have), formatValue(want))
+		}
+	}
+}
+
+// basicEqual 比较基础类型，非导出字段也可以比较
+func basicEqual(have, want reflect.Value) bool {
+	switch have.Kind() {
+	case reflect.Bool:
+		return have.Bool() == want.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return have.Int() == want.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return have.Uint() == want.Uint()
+	case reflect.Complex64, reflect.Complex128:
+		return have.Complex() == want.Complex()
+	case reflect.String:
+		return have.String() == want.String()
+	case reflect.Chan, reflect.UnsafePointer:
+		return have.Pointer() == want.Pointer()
+	}
+	return false
+}
+
+func formatValue(v reflect.Value) string {
+	const (
+		max = 256
+	)
+	if !v.IsValid() {
+		return "nil"
+	}
+
+	var s string
+	switch v.Kind() {
+	case reflect.String:
+		s = strconv.Quote(v.String())
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		if v.IsNil() {
+			return "nil"
+		}
+		if v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+			return formatValue(v.Elem())
+		}
+		s = fmt.Sprintf("%+v", v)
+	default:
+		if v.Type() == timeType && v.CanInterface() {
+			return v.Interface().(time.Time).Format(time.RFC3339Nano)
+		}
+		s = fmt.Sprintf("%+v", v)
+	}
+	if len(s) > max {
+		s = s[:max] + "..."
+	}
+	return s
+}
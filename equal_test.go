@@ -0,0 +1,101 @@
+package apitest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/donnol/apitest/testtype"
+)
+
+func TestEqualDiff(t *testing.T) {
+	now := time.Now()
+	have := testtype.UserModel{
+		Age:    18,
+		Salary: 100.001,
+		AddressList: []testtype.Address{
+			{Position: "a"},
+			{Position: "c"},
+		},
+		CreatedAt: now,
+	}
+	want := testtype.UserModel{
+		Age:    18,
+		Salary: 100,
+		AddressList: []testtype.Address{
+			{Position: "b"},
+			{Position: "c"},
+		},
+		CreatedAt: now.Add(time.Millisecond),
+	}
+
+	for _, tc := range []struct {
+		name  string
+		opts  []EqualOption
+		diffs []string
+	}{
+		{
+			name: "default",
+			diffs: []string{
+				".Salary: 100.001 != 100",
+				`.AddressList[0].Position: "a" != "b"`,
+				".CreatedAt: ",
+			},
+		},
+		{
+			name: "tolerance",
+			opts: []EqualOption{FloatTolerance(0.01), TimeTolerance(time.Second)},
+			diffs: []string{
+				`.AddressList[0].Position: "a" != "b"`,
+			},
+		},
+		{
+			name: "ignore",
+			opts: []EqualOption{IgnoreFields("Salary", "CreatedAt", ".AddressList[*].Position")},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := NewAT("/", http.MethodGet, "equal", nil, nil).EqualOptions(tc.opts...).Equal(have, want).Err()
+			if len(tc.diffs) == 0 {
+				if err != nil {
+					t.Fatalf("want nil, have %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("want error, have nil")
+			}
+			lines := strings.Split(err.Error(), "\n")
+			if lines[0] != "no.1 Not Equal:" || len(lines)-1 != len(tc.diffs) {
+				t.Fatalf("bad error: %v", err)
+			}
+			for i, diff := range tc.diffs {
+				if !strings.HasPrefix(lines[i+1], diff) {
+					t.Fatalf("line %d, have %s, want %s", i+1, lines[i+1], diff)
+				}
+			}
+		})
+	}
+}
+
+func TestEqualNilEqualsEmpty(t *testing.T) {
+	have := map[string][]int{"a": nil}
+	want := map[string][]int{"a": {}}
+
+	err := NewAT("/", http.MethodGet, "equal", nil, nil).Equal(have, want).Err()
+	if err == nil || !strings.Contains(err.Error(), `["a"]: nil != []`) {
+		t.Fatalf("bad error: %v", err)
+	}
+
+	if err := NewAT("/", http.MethodGet, "equal", nil, nil).EqualOptions(NilEqualsEmpty()).Equal(have, want).Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEqualTypeMismatch(t *testing.T) {
+	err := NewAT("/", http.MethodGet, "equal", nil, nil).Equal(1, uint(1)).Err()
+	if err == nil || !strings.Contains(err.Error(), "$: int(1) != uint(1)") {
+		t.Fatalf("bad error: %v", err)
+	}
+}
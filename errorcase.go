@@ -0,0 +1,154 @@
+package apitest
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrorCase 错误用例，使用Param发起请求，返回的错误与Error一致时才在文档的Error部分列出
+type ErrorCase struct {
+	Param    any         // 触发错误的参数，为nil时不带参数
+	Header   http.Header // 额外的header，如：错误的token；值为nil时去掉该header
+	Status   int         // 期望的状态码，为0时不校验
+	Error    any         // 期望的错误，APIError或错误结构体；错误结构体只比较其中的字段，响应里多出的字段忽略
+	CodePath string      // APIError的错误码在响应里的路径，默认为code
+	MsgPath  string      // APIError的错误信息在响应里的路径，默认为msg
+}
+
+// ErrorCases 依次执行错误用例并校验，全部通过后将错误追加到文档的Error部分
+func (at *AT) ErrorCases(cases ...ErrorCase) *AT {
+	if at.err != nil {
+		return at
+	}
+
+	errs := make([]any, 0, len(cases))
+	for i, c := range cases {
+		if c.Error == nil {
+			at.setErr(fmt.Errorf("error case no.%d: nil error", i+1))
+			return at
+		}
+		if err := at.runErrorCase(c); err != nil {
+			at.setErr(fmt.Errorf("error case no.%d %s: %w", i+1, errorCaseName(c.Error), err))
+			return at
+		}
+		errs = append(errs, c.Error)
+	}
+	at.ates = append(at.ates, errs...)
+
+	return at
+}
+
+func (at *AT) runErrorCase(c ErrorCase) error {
+	ex := at.newExchange()
+	ex.param = c.Param
+	if len(c.Header) > 0 {
+		header := make(http.Header, len(at.header)+len(c.Header))
+		// 键需要规范化，否则authorization不能覆盖或去掉Authorization
+		for k, v := range at.header {
+			header[http.CanonicalHeaderKey(k)] = v
+		}
+		for k, v := range c.Header {
+			header[http.CanonicalHeaderKey(k)] = v
+		}
+		ex.header = header
+	}
+	if err := at.prepare(ex); err != nil {
+		return err
+	}
+	at.send(ex)
+	if r := at.getHARRecorder(); r != nil {
+		r.add(at, ex)
+	}
+	if ex.err != nil {
+		return ex.err
+	}
+
+	data, _, err := copyResponseBody(ex.resp)
+	if err != nil {
+		return err
+	}
+	if c.Status != 0 && ex.resp.StatusCode != c.Status {
+		return fmt.Errorf("status code not equal, have %d, want %d, body: %s", ex.resp.StatusCode, c.Status, data)
+	}
+
+	isXML := at.resultFormat == "xml"
+	have, err := decodeTree(at.resultFormat, data)
+	if err != nil {
+		return err
+	}
+
+	if v, ok := c.Error.(APIError); ok {
+		codePath, msgPath := c.CodePath, c.MsgPath
+		if codePath == "" {
+			codePath = "code"
+		}
+		if msgPath == "" {
+			msgPath = "msg"
+		}
+		var diffs []string
+		for _, item := range []struct{ path, want string }{
+			{codePath, v.Code()},
+			{msgPath, v.Msg()},
+		} {
+			got, err := lookupPath(have, item.path, isXML)
+			if err != nil {
+				return err
+			}
+			if fmt.Sprint(got) != item.want {
+				diffs = append(diffs, fmt.Sprintf("%s: have %s, want %q", item.path, fragment(got), item.want))
+			}
+		}
+		if len(diffs) > 0 {
+			return fmt.Errorf("error not equal:\n%s", strings.Join(diffs, "\n"))
+		}
+		return nil
+	}
+
+	var wantData []byte
+	if isXML {
+		wantData, err = xml.Marshal(c.Error)
+	} else {
+		wantData, err = json.Marshal(c.Error)
+	}
+	if err != nil {
+		return err
+	}
+	want, err := decodeTree(at.resultFormat, wantData)
+	if err != nil {
+		return err
+	}
+	if diffs := diffTree("", pruneTree(have, want), want); len(diffs) > 0 {
+		return fmt.Errorf("error not equal:\n%s", strings.Join(diffs, "\n"))
+	}
+
+	return nil
+}
+
+// pruneTree 只保留want里有的键
+func pruneTree(have, want any) any {
+	wm, ok := want.(map[string]any)
+	if !ok {
+		return have
+	}
+	hm, ok := have.(map[string]any)
+	if !ok {
+		return have
+	}
+	r := make(map[string]any, len(wm))
+	for k, wv := range wm {
+		if hv, ok := hm[k]; ok {
+			r[k] = pruneTree(hv, wv)
+		}
+	}
+	return r
+}
+
+func errorCaseName(e any) string {
+	if v, ok := e.(APIError); ok {
+		return "code " + v.Code()
+	}
+	return fmt.Sprintf("%T", e)
+}
@@ -0,0 +1,83 @@
+package apitest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type (
+	errorCaseParam struct {
+		Id int `json:"id"`
+	}
+	errorCaseError struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+	}
+)
+
+func errorCaseMux() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		switch {
+		case r.Header.Get("Authorization") == "":
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code":"1002","msg":"未登录","traceId":"x"}`))
+		case r.URL.Query().Get("id") == "0":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code":"1001","msg":"用户不存在"}`))
+		default:
+			w.Write([]byte(`{"code":"0","msg":"ok"}`))
+		}
+	})
+	return mux
+}
+
+func TestErrorCases(t *testing.T) {
+	header := http.Header{"Authorization": []string{"abc"}}
+	at := NewAT("/user", http.MethodGet, "获取用户", header, nil).
+		SetHandler(errorCaseMux()).
+		SetParam(&errorCaseParam{Id: 1}).
+		Run().
+		EqualCode(http.StatusOK).
+		ErrorCases(
+			ErrorCase{
+				Param:  &errorCaseParam{Id: 0},
+				Status: http.StatusBadRequest,
+				Error:  apiError{"1001", "用户不存在"},
+			},
+			ErrorCase{
+				Param:  &errorCaseParam{Id: 1},
+				Header: http.Header{"authorization": nil}, // 与at的header大小写不同
+				Status: http.StatusUnauthorized,
+				Error:  &errorCaseError{Code: "1002", Msg: "未登录"},
+			},
+		)
+	if err := at.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := structToList(errorName, at.ates...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Error\n\n* `1001` 用户不存在\n* {\"code\":\"1002\",\"msg\":\"未登录\"}\n\n"
+	if list != want {
+		t.Fatalf("bad list, have %q, want %q", list, want)
+	}
+
+	// 错误用例未通过时，不列出
+	at = NewAT("/user", http.MethodGet, "获取用户", header, nil).
+		SetHandler(errorCaseMux()).
+		ErrorCases(ErrorCase{
+			Param: &errorCaseParam{Id: 0},
+			Error: apiError{"1001", "用户不存在了"},
+		})
+	if err := at.Err(); err == nil || !strings.Contains(err.Error(), `msg: have "用户不存在", want "用户不存在了"`) {
+		t.Fatalf("bad error: %v", err)
+	}
+	if len(at.ates) != 0 {
+		t.Fatalf("failed case should not be documented: %v", at.ates)
+	}
+}
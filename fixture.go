@@ -0,0 +1,210 @@
+package apitest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	fixtureRecord = "record"
+	fixtureReplay = "replay"
+)
+
+// ErrFixtureNotFound 回放时找不到对应的响应
+var ErrFixtureNotFound = errors.New("fixture not found")
+
+// Record 录制模式：Run发出真实请求，并将响应保存到dir目录下，文件以apiKey和参数的哈希命名
+func (at *AT) Record(dir string) *AT {
+	at.fixtureMode = fixtureRecord
+	at.fixtureDir = dir
+	return at
+}
+
+// Replay 回放模式：Run不再发出请求，而是使用Record保存在dir目录下的响应
+func (at *AT) Replay(dir string) *AT {
+	at.fixtureMode = fixtureReplay
+	at.fixtureDir = dir
+	return at
+}
+
+type (
+	fixture struct {
+		Key      string          `json:"key"`
+		Request  fixtureRequest  `json:"request"`
+		Response fixtureResponse `json:"response"`
+	}
+
+	fixtureRequest struct {
+		Method string `json:"method"`
+		URL    string `json:"url"`
+		Body   string `json:"body,omitempty"`
+	}
+
+	fixtureResponse struct {
+		StatusCode   int         `json:"statusCode"`
+		Header       http.Header `json:"header"`
+		Body         string      `json:"body"`
+		BodyEncoding string      `json:"bodyEncoding,omitempty"` // 非utf8内容使用base64
+	}
+)
+
+// fixtureMu 压力测试录制时，同一参数的请求会写同一个文件
+var fixtureMu sync.Mutex
+
+// fixtureFile 如：dir/POST_user_id-1a2b3c4d5e6f7a8b.json，哈希由查询字符串和参数计算得到
+func (at *AT) fixtureFile(ex *exchange) (string, error) {
+	key := apiKey(at.path, at.method)
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return '_'
+	}, at.method+at.path)
+
+	h := sha256.New()
+	io.WriteString(h, key+"\n"+ex.req.URL.RawQuery+"\n")
+	if err := at.writeFixtureParam(h, ex); err != nil {
+		return "", err
+	}
+	return filepath.Join(at.fixtureDir, name+"-"+hex.EncodeToString(h.Sum(nil))[:16]+".json"), nil
+}
+
+// writeFixtureParam 写入参数用于计算哈希；multipart的请求体带有随机的boundary，
+// 所以改为依次写入字段的名字和值，以及文件的名字、文件名和内容的哈希
+func (at *AT) writeFixtureParam(w io.Writer, ex *exchange) error {
+	switch {
+	case at.file != "":
+		data, err := os.ReadFile(at.file)
+		if err != nil {
+			return err
+		}
+		writeFixtureFile(w, "file", at.file, data)
+	case at.multipart != nil:
+		fields, err := paramFields(ex.param)
+		if err != nil {
+			return err
+		}
+		for _, field := range fields {
+			fmt.Fprintf(w, "field %q=%q\n", field.name, field.value)
+		}
+		for _, part := range at.multipart.parts {
+			if !part.isFile {
+				fmt.Fprintf(w, "field %q=%q\n", part.name, part.value)
+				continue
+			}
+			data, err := part.content()
+			if err != nil {
+				return err
+			}
+			writeFixtureFile(w, part.name, part.fileName, data)
+		}
+	default:
+		w.Write(ex.reqBody)
+	}
+	return nil
+}
+
+func writeFixtureFile(w io.Writer, name, fileName string, data []byte) {
+	sum := sha256.Sum256(data)
+	fmt.Fprintf(w, "file %q=%q %s\n", name, fileName, hex.EncodeToString(sum[:]))
+}
+
+// replay 读取保存的响应
+func (at *AT) replay(ex *exchange) (*http.Response, error) {
+	file, err := at.fixtureFile(ex)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s %s, file: %s, please run with Record first", ErrFixtureNotFound, at.method, ex.req.URL.String(), file)
+		}
+		return nil, err
+	}
+
+	var f fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("decode fixture %s failed: %w", file, err)
+	}
+
+	body := []byte(f.Response.Body)
+	if f.Response.BodyEncoding == "base64" {
+		body, err = base64.StdEncoding.DecodeString(f.Response.Body)
+		if err != nil {
+			return nil, fmt.Errorf("decode fixture %s body failed: %w", file, err)
+		}
+	}
+	header := f.Response.Header
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.Response.StatusCode, http.StatusText(f.Response.StatusCode)),
+		StatusCode:    f.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       ex.req,
+	}, nil
+}
+
+// record 保存响应
+func (at *AT) record(ex *exchange) error {
+	data, _, err := copyResponseBody(ex.resp)
+	if err != nil {
+		return err
+	}
+
+	f := fixture{
+		Key: apiKey(at.path, at.method),
+		Request: fixtureRequest{
+			Method: ex.req.Method,
+			URL:    ex.req.URL.String(),
+			Body:   string(ex.reqBody),
+		},
+		Response: fixtureResponse{
+			StatusCode: ex.resp.StatusCode,
+			Header:     ex.resp.Header,
+		},
+	}
+	if utf8.Valid(data) {
+		f.Response.Body = string(data)
+	} else {
+		f.Response.Body = base64.StdEncoding.EncodeToString(data)
+		f.Response.BodyEncoding = "base64"
+	}
+	content, err := json.MarshalIndent(f, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	file, err := at.fixtureFile(ex)
+	if err != nil {
+		return err
+	}
+
+	fixtureMu.Lock()
+	defer fixtureMu.Unlock()
+
+	if err := os.MkdirAll(at.fixtureDir, os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(file, append(content, '\n'), 0o644)
+}
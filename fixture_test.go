@@ -0,0 +1,115 @@
+package apitest
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRecordReplay(t *testing.T) {
+	var count int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/book", func(w http.ResponseWriter, r *http.Request) {
+		count++
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("X-Count", "1")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":` + r.URL.Query().Get("id") + `,"name":"apitest"}`))
+	})
+
+	dir := t.TempDir()
+	for _, id := range []uint{1, 2} {
+		if err := NewAT("/book", http.MethodGet, "获取图书", nil, nil).
+			SetHandler(mux).
+			Record(dir).
+			SetParam(&bookParam{Id: id}).
+			Run().
+			EqualCode(http.StatusCreated).
+			Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 || len(files) != 2 {
+		t.Fatalf("bad record, count: %d, files: %d", count, len(files))
+	}
+
+	// 回放不再经过handler
+	for _, id := range []uint{2, 1} {
+		var r bookResult
+		if err := NewAT("/book", http.MethodGet, "获取图书", nil, nil).
+			Replay(dir).
+			SetParam(&bookParam{Id: id}).
+			Run().
+			EqualCode(http.StatusCreated).
+			EqualHeader("X-Count", "1").
+			Result(&r).
+			Equal(r.Id, id, r.Name, "apitest").
+			Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if count != 2 {
+		t.Fatalf("replay should not do request, count: %d", count)
+	}
+
+	// 参数不同，找不到响应
+	err = NewAT("/book", http.MethodGet, "获取图书", nil, nil).
+		Replay(dir).
+		SetParam(&bookParam{Id: 3}).
+		Run().
+		Err()
+	if !errors.Is(err, ErrFixtureNotFound) {
+		t.Fatalf("bad error: %v", err)
+	}
+}
+
+func TestRecordReplayMultipart(t *testing.T) {
+	var count int
+	mux := http.NewServeMux()
+	handler := multipartMux()
+	mux.HandleFunc("/import", func(w http.ResponseWriter, r *http.Request) {
+		count++
+		handler.ServeHTTP(w, r)
+	})
+
+	newAT := func(avatar string) *AT {
+		return NewAT("/import", http.MethodPost, "导入用户", nil, nil).
+			SetHandler(mux).
+			SetParam(&importParam{Source: "excel", Tags: []string{"a"}}).
+			SetMultipart(NewMultipart().
+				Field("remark", "first").
+				File("users", "testdata/upload/users.csv", "").
+				Reader("avatar", "avatar.png", "image/png", strings.NewReader(avatar)))
+	}
+
+	dir := t.TempDir()
+	if err := newAT("png").Record(dir).Run().EqualCode(http.StatusOK).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// boundary每次都不同，回放仍然能找到响应
+	var r importResult
+	if err := newAT("png").
+		Replay(dir).
+		Run().
+		EqualCode(http.StatusOK).
+		Result(&r).
+		Err(); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 || r.Fields["remark"][0] != "first" || r.Files["avatar"].Content != "png" {
+		t.Fatalf("bad replay, count: %d, result: %+v", count, r)
+	}
+
+	// 文件内容不同，找不到响应
+	err := newAT("jpg").Replay(dir).Run().Err()
+	if !errors.Is(err, ErrFixtureNotFound) {
+		t.Fatalf("bad error: %v", err)
+	}
+}
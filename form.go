@@ -0,0 +1,162 @@
+package apitest
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	formParamFormat = "form"
+	formContentType = "application/x-www-form-urlencoded"
+	paramInForm     = "Form"
+)
+
+// UseFormParamFormat 设置参数格式为application/x-www-form-urlencoded；
+// 嵌套结构体的键为a.b，切片为重复的键，结构体切片的键为list[0].name，与multipart的字段相同；
+// 查询字符串只展开第一层字段，嵌套结构体的值为%v格式，两者不同
+func (at *AT) UseFormParamFormat() *AT {
+	at.paramFormat = formParamFormat
+	return at
+}
+
+// paramLabel 文档里参数的位置，如：Query、Body、Form
+func (at *AT) paramLabel() string {
+	position := at.paramPosition()
+	if position == paramInBody && at.paramFormat == formParamFormat {
+		return paramInForm
+	}
+	return position
+}
+
+// formValues 将结构体参数编码为表单
+func formValues(v any) (url.Values, error) {
+	vtype, vvalue, err := structTypeValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(url.Values)
+	encodeFormStruct(values, "", vtype, vvalue)
+	return values, nil
+}
+
+func encodeFormStruct(values url.Values, prefix string, vtype reflect.Type, vvalue reflect.Value) {
+	for i := 0; i < vtype.NumField(); i++ {
+		field := vtype.Field(i)
+		value := vvalue.Field(i)
+
+		if field.PkgPath != "" && !field.Anonymous { // 忽略非导出字段
+			continue
+		}
+
+		// 匿名结构体
+		if field.Anonymous {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Ptr {
+				if value.IsNil() {
+					continue
+				}
+				fieldType = fieldType.Elem()
+				value = value.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				encodeFormStruct(values, prefix, fieldType, value)
+			}
+			continue
+		}
+
+		name, ok := fieldName(field)
+		if !ok {
+			continue
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		encodeFormValue(values, name, value)
+	}
+}
+
+func encodeFormValue(values url.Values, name string, value reflect.Value) {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() { // nil则忽略
+			return
+		}
+		value = value.Elem()
+	}
+
+	if value.CanInterface() {
+		if m, ok := value.Interface().(encoding.TextMarshaler); ok {
+			text, err := m.MarshalText()
+			if err == nil {
+				values.Add(name, string(text))
+				return
+			}
+		}
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		encodeFormStruct(values, name, value.Type(), value)
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8 { // []byte
+			values.Add(name, string(value.Bytes()))
+			return
+		}
+		for i := 0; i < value.Len(); i++ {
+			item := value.Index(i)
+			if isFormScalar(item.Type()) {
+				encodeFormValue(values, name, item)
+				continue
+			}
+			encodeFormValue(values, fmt.Sprintf("%s[%d]", name, i), item)
+		}
+	case reflect.Map:
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+		})
+		for _, key := range keys {
+			encodeFormValue(values, fmt.Sprintf("%s[%v]", name, key), value.MapIndex(key))
+		}
+	default:
+		values.Add(name, fmt.Sprintf("%v", value))
+	}
+}
+
+// isFormScalar 切片元素是否可以直接使用重复的键
+func isFormScalar(typ reflect.Type) bool {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Implements(reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()) {
+		return true
+	}
+	switch typ.Kind() {
+	case reflect.Struct, reflect.Slice, reflect.Array, reflect.Map, reflect.Interface:
+		return false
+	}
+	return true
+}
+
+// fieldName 根据tag获取字段名，规则与structToMap相同；返回false时忽略该字段
+func fieldName(field reflect.StructField) (string, bool) {
+	name := getFieldNameByTag(field.Tag)
+	if name == "-" { // 忽略字段
+		return "", false
+	}
+
+	if name == "" { // 使用默认名
+		name = strings.ToLower(string(field.Name[0])) // 字段名首字母小写
+		if len(field.Name) > 1 {
+			name += field.Name[1:]
+		}
+	} else { // 多个部分时，使用第一个部分
+		split := strings.Split(name, ",")
+		name = strings.TrimSpace(split[0])
+	}
+	return name, true
+}
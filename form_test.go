@@ -0,0 +1,107 @@
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type (
+	formAddr struct {
+		City string `json:"city"`
+	}
+	formParam struct {
+		formAddr
+		GrantType string            `json:"grant_type"`
+		Scope     []string          `json:"scope"`
+		Addr      formAddr          `json:"addr"`
+		List      []formAddr        `json:"list"`
+		Extra     map[string]string `json:"extra"`
+		Expire    time.Time         `json:"expire"`
+		Nil       *string           `json:"nil"`
+		Ignore    string            `json:"-"`
+		NoTag     int
+	}
+	formResult struct {
+		GrantType []string `json:"grant_type"`
+		Scope     []string `json:"scope"`
+	}
+)
+
+func TestFormValues(t *testing.T) {
+	values, err := formValues(&formParam{
+		formAddr:  formAddr{City: "gz"},
+		GrantType: "password",
+		Scope:     []string{"read", "write"},
+		Addr:      formAddr{City: "sz"},
+		List:      []formAddr{{City: "a"}, {City: "b"}},
+		Extra:     map[string]string{"k": "v"},
+		Expire:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Ignore:    "x",
+		NoTag:     1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "addr.city=sz&city=gz&expire=2024-01-02T03%3A04%3A05Z&extra%5Bk%5D=v&grant_type=password&list%5B0%5D.city=a&list%5B1%5D.city=b&noTag=1&scope=read&scope=write"
+	if have := values.Encode(); have != want {
+		t.Fatalf("bad values, have %s, want %s", have, want)
+	}
+}
+
+func TestFormParamFormat(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != formContentType {
+			http.Error(w, "bad content type", http.StatusBadRequest)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(r.PostForm)
+	})
+
+	var r formResult
+	at := NewAT("/oauth/token", http.MethodPost, "获取token", nil, nil).
+		SetHandler(mux).
+		UseFormParamFormat().
+		SetParam(&formParam{GrantType: "password", Scope: []string{"read", "write"}}).
+		Run().
+		EqualCode(http.StatusOK).
+		Result(&r)
+	if err := at.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Scope) != 2 || r.Scope[1] != "write" || len(r.GrantType) != 1 || r.GrantType[0] != "password" {
+		t.Fatalf("bad form: %v", r)
+	}
+
+	item, err := at.postmanItem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := item.Request.Body; body == nil || body.Mode != "urlencoded" || len(body.URLEncoded) != 7 {
+		t.Fatalf("bad postman body: %+v", body)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := at.WriteFile(buf).Err(); err != nil {
+		t.Fatal(err)
+	}
+	doc := buf.String()
+	for _, want := range []string{
+		"Param - Form",
+		"- Content-Type: " + formContentType,
+		"city=&expire=0001-01-01T00%3A00%3A00Z&grant_type=password",
+	} {
+		if !strings.Contains(doc, want) {
+			t.Fatalf("doc should contain %q, have:\n%s", want, doc)
+		}
+	}
+}
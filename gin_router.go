@@ -145,7 +145,7 @@ const (
 		}
 		var body;
 		if(paramValue != '') {
-			if(method == 'get' || method == 'delete') {
+			if(method == 'get' || method == 'delete' || method == 'head' || method == 'options') {
 				path += '?'+paramValue;
 			}else{
 				body = paramValue;
@@ -2,6 +2,7 @@ package apitest
 
 import (
 	"fmt"
+	"net/http"
 	"reflect"
 	"strconv"
 	"strings"
@@ -21,6 +22,8 @@ const (
 type Collector struct {
 	opt *Option
 
+	handler http.Handler // 处理请求的handler，生成的AT会在进程内使用它运行
+
 	testAPIs    map[string]*TestAPI
 	testAPIKeys []string
 }
@@ -28,6 +31,7 @@ type Collector struct {
 type Option struct {
 	basePath string
 	group    *gin.RouterGroup
+	handler  http.Handler // 与group对应的handler，一般是group所属的*gin.Engine
 
 	paramIndex  int // 参数位置
 	resultIndex int // 结果位置
@@ -59,6 +63,13 @@ func WithRouterGroup(group *gin.RouterGroup) Setter {
 	}
 }
 
+// WithHandler 指定group所属的handler，生成的AT将在进程内使用它运行
+func WithHandler(handler http.Handler) Setter {
+	return func(o *Option) {
+		o.handler = handler
+	}
+}
+
 func WithParamIndex(paramIndex int) Setter {
 	return func(o *Option) {
 		o.paramIndex = paramIndex
@@ -110,8 +121,10 @@ func NewCollector(
 		gin.SetMode(gin.ReleaseMode)
 		engine := gin.Default()
 		apiGroup = engine.Group(opt.basePath)
+		collector.handler = engine
 	} else {
 		apiGroup = opt.group
+		collector.handler = opt.handler
 	}
 
 	// test api
@@ -159,6 +172,9 @@ func NewCollector(
 		if route.Opt.ResultFormat == "xml" {
 			at.UseXMLResultFormat()
 		}
+		if collector.handler != nil {
+			at.SetHandler(collector.handler)
+		}
 		m[key] = &TestAPI{
 			AT:            at,
 			key:           key,
@@ -227,6 +243,11 @@ func GenParamResult(key string, param, result reflect.Type, resultWrapper ...fun
 	return
 }
 
+// Handler 返回处理请求的handler，如NewCollector内部新建的*gin.Engine
+func (c *Collector) Handler() http.Handler {
+	return c.handler
+}
+
 func (c *Collector) TestAPIs() map[string]*TestAPI {
 	return c.testAPIs
 }
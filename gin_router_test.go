@@ -3,14 +3,18 @@ package apitest
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"io"
 	"net/http"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/donnol/do"
 	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
 )
 
 func TestGinHandlerAPIDoc(t *testing.T) {
@@ -192,3 +196,60 @@ func TestGinHandlerAPIDoc(t *testing.T) {
 		t.Fatalf("bad result, don't have book or user route")
 	}
 }
+
+type (
+	bookParam struct {
+		Id uint `json:"id" form:"id"` // 图书id
+	}
+	bookResult struct {
+		Id   uint   `json:"id"`   // 图书id
+		Name string `json:"name"` // 书名
+	}
+	bookAPI struct{}
+)
+
+func getBook(ctx context.Context, p bookParam) (bookResult, error) {
+	return bookResult{Id: p.Id, Name: "apitest"}, nil
+}
+
+func (bookAPI) RegisterAPI(group *gin.RouterGroup) []*Route {
+	group.GET("/book", func(c *gin.Context) {
+		var p bookParam
+		if err := c.ShouldBindQuery(&p); err != nil {
+			c.JSON(http.StatusBadRequest, Result[any]{Code: 1, Msg: err.Error()})
+			return
+		}
+		r, _ := getBook(c, p)
+		c.JSON(http.StatusOK, Result[bookResult]{Data: r})
+	})
+
+	return []*Route{
+		do.NewRoute(http.MethodGet, "/book", "获取图书信息", func(c *gin.Context) {}),
+	}
+}
+
+func TestCollectorHandler(t *testing.T) {
+	collector := NewCollector(bookAPI{}, map[string]lo.Tuple2[reflect.Value, int]{
+		ApiKey(http.MethodGet, "/book"): lo.T2(reflect.ValueOf(getBook), 1),
+	})
+	if collector.Handler() == nil {
+		t.Fatal("nil handler")
+	}
+
+	apis := collector.FindTestAPIsByPrefix("/api/book")
+	if len(apis) != 1 {
+		t.Fatalf("bad api number: %d", len(apis))
+	}
+
+	var r Result[bookResult]
+	if err := apis[0].SetParam(&bookParam{Id: 1}).
+		Run().
+		EqualCode(http.StatusOK).
+		Result(&r).
+		Err(); err != nil {
+		t.Fatal(err)
+	}
+	if r.Data.Id != 1 || r.Data.Name != "apitest" {
+		t.Fatalf("bad result: %+v", r)
+	}
+}
@@ -0,0 +1,306 @@
+package apitest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	harVersion = "1.2"
+)
+
+type (
+	// HARRecorder 收集Run发出的请求和响应，写为HAR文件后可以在浏览器等工具里查看和重放
+	HARRecorder struct {
+		mu      sync.Mutex
+		entries []HAREntry
+	}
+
+	HAR struct {
+		Log HARLog `json:"log"`
+	}
+
+	HARLog struct {
+		Version string     `json:"version"`
+		Creator HARCreator `json:"creator"`
+		Entries []HAREntry `json:"entries"`
+	}
+
+	HARCreator struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+
+	HAREntry struct {
+		StartedDateTime string      `json:"startedDateTime"`
+		Time            float64     `json:"time"` // 毫秒
+		Request         HARRequest  `json:"request"`
+		Response        HARResponse `json:"response"`
+		Cache           struct{}    `json:"cache"`
+		Timings         HARTimings  `json:"timings"`
+		Comment         string      `json:"comment,omitempty"`
+	}
+
+	HARRequest struct {
+		Method      string         `json:"method"`
+		URL         string         `json:"url"`
+		HTTPVersion string         `json:"httpVersion"`
+		Cookies     []HARNameValue `json:"cookies"`
+		Headers     []HARNameValue `json:"headers"`
+		QueryString []HARNameValue `json:"queryString"`
+		PostData    *HARPostData   `json:"postData,omitempty"`
+		HeadersSize int            `json:"headersSize"`
+		BodySize    int            `json:"bodySize"`
+	}
+
+	HARResponse struct {
+		Status      int            `json:"status"`
+		StatusText  string         `json:"statusText"`
+		HTTPVersion string         `json:"httpVersion"`
+		Cookies     []HARNameValue `json:"cookies"`
+		Headers     []HARNameValue `json:"headers"`
+		Content     HARContent     `json:"content"`
+		RedirectURL string         `json:"redirectURL"`
+		HeadersSize int            `json:"headersSize"`
+		BodySize    int            `json:"bodySize"`
+	}
+
+	HARNameValue struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+
+	HARPostData struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+		Encoding string `json:"encoding,omitempty"`
+	}
+
+	HARContent struct {
+		Size     int    `json:"size"`
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text,omitempty"`
+		Encoding string `json:"encoding,omitempty"` // 非utf8内容使用base64
+	}
+
+	HARTimings struct {
+		Send    float64 `json:"send"`
+		Wait    float64 `json:"wait"`
+		Receive float64 `json:"receive"`
+	}
+)
+
+var (
+	harMu       sync.Mutex
+	harRecorder *HARRecorder
+)
+
+// NewHARRecorder 新建recorder
+func NewHARRecorder() *HARRecorder {
+	return &HARRecorder{}
+}
+
+// StartHARRecorder 开启全局recorder，之后所有AT的Run都会被记录，一般在TestMain里使用：
+//
+//	r := apitest.StartHARRecorder()
+//	code := m.Run()
+//	r.Stop()
+//	r.WriteFile("testdata/run.har")
+func StartHARRecorder() *HARRecorder {
+	harMu.Lock()
+	defer harMu.Unlock()
+
+	harRecorder = NewHARRecorder()
+	return harRecorder
+}
+
+// Stop 停止作为全局recorder，已记录的内容保留
+func (r *HARRecorder) Stop() {
+	harMu.Lock()
+	defer harMu.Unlock()
+
+	if harRecorder == r {
+		harRecorder = nil
+	}
+}
+
+// SetHARRecorder 使用指定的recorder记录当前AT的请求
+func (at *AT) SetHARRecorder(r *HARRecorder) *AT {
+	at.har = r
+	return at
+}
+
+func (at *AT) getHARRecorder() *HARRecorder {
+	if at.har != nil {
+		return at.har
+	}
+
+	harMu.Lock()
+	defer harMu.Unlock()
+	return harRecorder
+}
+
+// Entries 已记录的请求
+func (r *HARRecorder) Entries() []HAREntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := make([]HAREntry, len(r.entries))
+	copy(entries, r.entries)
+	return entries
+}
+
+// WriteTo 以json格式写入
+func (r *HARRecorder) WriteTo(w io.Writer) (int64, error) {
+	har := HAR{
+		Log: HARLog{
+			Version: harVersion,
+			Creator: HARCreator{Name: "apitest", Version: harVersion},
+			Entries: r.Entries(),
+		},
+	}
+	data, err := json.MarshalIndent(har, "", "    ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(append(data, '\n'))
+	return int64(n), err
+}
+
+// WriteFile 写入HAR文件
+func (r *HARRecorder) WriteFile(file string) error {
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_TRUNC|os.O_RDWR, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = r.WriteTo(f)
+	return err
+}
+
+func (r *HARRecorder) add(at *AT, ex *exchange) {
+	req := ex.req
+	ms := float64(ex.used) / float64(time.Millisecond)
+	entry := HAREntry{
+		StartedDateTime: ex.start.Format(time.RFC3339Nano),
+		Time:            ms,
+		Request: HARRequest{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			HTTPVersion: harHTTPVersion(req.Proto),
+			Cookies:     harCookies(req.Cookies()),
+			Headers:     harHeaders(req.Header),
+			QueryString: harQuery(req),
+			HeadersSize: -1,
+			BodySize:    len(ex.reqBody),
+		},
+		Timings: HARTimings{Wait: ms},
+		Comment: at.comment,
+	}
+	if len(ex.reqBody) > 0 {
+		entry.Request.PostData = &HARPostData{
+			MimeType: req.Header.Get("Content-Type"),
+		}
+		// 上传的文件等二进制内容使用base64
+		if utf8.Valid(ex.reqBody) {
+			entry.Request.PostData.Text = string(ex.reqBody)
+		} else {
+			entry.Request.PostData.Text = base64.StdEncoding.EncodeToString(ex.reqBody)
+			entry.Request.PostData.Encoding = "base64"
+		}
+	}
+
+	if ex.err != nil {
+		// 请求失败时也记录下来，便于重放
+		entry.Response = HARResponse{
+			Cookies:     []HARNameValue{},
+			Headers:     []HARNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		}
+		entry.Comment += ": " + ex.err.Error()
+	} else if resp := ex.resp; resp != nil {
+		data, _, _ := copyResponseBody(resp)
+		content := HARContent{
+			Size:     len(data),
+			MimeType: resp.Header.Get("Content-Type"),
+		}
+		if utf8.Valid(data) {
+			content.Text = string(data)
+		} else {
+			content.Text = base64.StdEncoding.EncodeToString(data)
+			content.Encoding = "base64"
+		}
+		entry.Response = HARResponse{
+			Status:      resp.StatusCode,
+			StatusText:  http.StatusText(resp.StatusCode),
+			HTTPVersion: harHTTPVersion(resp.Proto),
+			Cookies:     harCookies(resp.Cookies()),
+			Headers:     harHeaders(resp.Header),
+			Content:     content,
+			RedirectURL: resp.Header.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    len(data),
+		}
+	}
+
+	r.mu.Lock()
+	r.entries = append(r.entries, entry)
+	r.mu.Unlock()
+}
+
+func harHTTPVersion(proto string) string {
+	if proto == "" {
+		return "HTTP/1.1"
+	}
+	return proto
+}
+
+func harHeaders(h http.Header) []HARNameValue {
+	keys := make([]string, 0, len(h))
+	for key := range h {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	r := make([]HARNameValue, 0, len(keys))
+	for _, key := range keys {
+		for _, value := range h[key] {
+			r = append(r, HARNameValue{Name: key, Value: value})
+		}
+	}
+	return r
+}
+
+func harCookies(cookies []*http.Cookie) []HARNameValue {
+	r := make([]HARNameValue, 0, len(cookies))
+	for _, c := range cookies {
+		r = append(r, HARNameValue{Name: c.Name, Value: c.Value})
+	}
+	return r
+}
+
+func harQuery(req *http.Request) []HARNameValue {
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	r := make([]HARNameValue, 0, len(keys))
+	for _, key := range keys {
+		for _, value := range query[key] {
+			r = append(r, HARNameValue{Name: key, Value: value})
+		}
+	}
+	return r
+}
@@ -0,0 +1,112 @@
+package apitest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestHARRecorder(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":1}`))
+	})
+
+	r := StartHARRecorder()
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		if err := NewAT("/user", method, "用户", nil, nil).
+			SetHandler(mux).
+			SetParam(&struct {
+				Name string `json:"name"`
+			}{Name: "jd"}).
+			Run().
+			Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 虚假执行不会被记录
+	NewAT("/user", http.MethodGet, "用户", nil, nil).SetParam(&struct{}{}).FakeRun()
+	r.Stop()
+	if err := NewAT("/user", http.MethodGet, "用户", nil, nil).SetHandler(mux).SetParam(&struct{}{}).Run().Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := r.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("bad entry number: %d", len(entries))
+	}
+	get, post := entries[0], entries[1]
+	if get.Request.Method != http.MethodGet || get.Request.URL != "http://localhost:80/user?name=jd" ||
+		len(get.Request.QueryString) != 1 || get.Request.PostData != nil {
+		t.Errorf("bad get request: %+v", get.Request)
+	}
+	if post.Request.PostData == nil || post.Request.PostData.Text != `{"name":"jd"}` {
+		t.Errorf("bad post request: %+v", post.Request)
+	}
+	if post.Response.Status != http.StatusOK || post.Response.Content.Text != `{"id":1}` ||
+		post.Response.Content.MimeType != "application/json" || len(post.Response.Cookies) != 1 {
+		t.Errorf("bad response: %+v", post.Response)
+	}
+
+	buf := new(bytes.Buffer)
+	if _, err := r.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	var har HAR
+	if err := json.Unmarshal(buf.Bytes(), &har); err != nil {
+		t.Fatal(err)
+	}
+	if har.Log.Version != "1.2" || len(har.Log.Entries) != 2 {
+		t.Fatalf("bad har: %s", buf.Bytes())
+	}
+
+	// 指定recorder，失败的请求也会被记录
+	own := NewHARRecorder()
+	if err := NewAT("/user", http.MethodGet, "用户", nil, nil).
+		SetHost("127.0.0.1:1").
+		SetHARRecorder(own).
+		SetParam(&struct{}{}).
+		Run().
+		Err(); err == nil {
+		t.Fatal("want error, but got nil")
+	}
+	if entries := own.Entries(); len(entries) != 1 || entries[0].Response.Status != 0 || entries[0].Comment == "用户" {
+		t.Fatalf("bad failed entry: %+v", entries)
+	}
+}
+
+func TestHARRecorderBinaryBody(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {})
+
+	data := []byte{0xff, 0xfe, 0x00, 0x01}
+	r := NewHARRecorder()
+	if err := NewAT("/upload", http.MethodPost, "上传", nil, nil).
+		SetHandler(mux).
+		SetHARRecorder(r).
+		SetMultipart(NewMultipart().Reader("file", "a.bin", "", bytes.NewReader(data))).
+		Run().
+		Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := r.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("bad entry number: %d", len(entries))
+	}
+	postData := entries[0].Request.PostData
+	if postData == nil || postData.Encoding != "base64" {
+		t.Fatalf("bad post data: %+v", postData)
+	}
+	body, err := base64.StdEncoding.DecodeString(postData.Text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(body, data) {
+		t.Fatalf("bad body: %q", body)
+	}
+}
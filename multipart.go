@@ -0,0 +1,281 @@
+package apitest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	paramInMultipart = "Multipart"
+)
+
+type (
+	// Multipart multipart/form-data请求体，可以包含多个字段和文件；AT的参数也会作为字段写入，且在其他部分之前
+	Multipart struct {
+		parts []*multipartPart
+		err   error
+	}
+
+	multipartPart struct {
+		name        string
+		value       string // 字段的值
+		isFile      bool
+		fileName    string
+		contentType string
+		path        string // 文件路径，在发起请求时读取
+		data        []byte // 内存里的文件内容
+	}
+)
+
+// NewMultipart 新建
+func NewMultipart() *Multipart {
+	return &Multipart{}
+}
+
+// Field 添加字段
+func (m *Multipart) Field(name, value string) *Multipart {
+	m.parts = append(m.parts, &multipartPart{name: name, value: value})
+	return m
+}
+
+// File 添加文件，contentType为空时根据扩展名判断
+func (m *Multipart) File(name, path, contentType string) *Multipart {
+	if path == "" {
+		m.setErr(fmt.Errorf("empty file for part %s", name))
+		return m
+	}
+	m.parts = append(m.parts, &multipartPart{
+		name:        name,
+		isFile:      true,
+		fileName:    filepath.Base(path),
+		contentType: fileContentType(path, contentType),
+		path:        path,
+	})
+	return m
+}
+
+// Reader 添加内存里的文件，r会被立即读完，以便重复发起请求
+func (m *Multipart) Reader(name, fileName, contentType string, r io.Reader) *Multipart {
+	if r == nil {
+		m.setErr(fmt.Errorf("nil reader for part %s", name))
+		return m
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		m.setErr(fmt.Errorf("read part %s failed: %w", name, err))
+		return m
+	}
+	m.parts = append(m.parts, &multipartPart{
+		name:        name,
+		isFile:      true,
+		fileName:    fileName,
+		contentType: fileContentType(fileName, contentType),
+		data:        data,
+	})
+	return m
+}
+
+func (m *Multipart) setErr(err error) {
+	if m.err == nil {
+		m.err = err
+	}
+}
+
+// SetMultipart 使用multipart/form-data发送参数和文件
+func (at *AT) SetMultipart(m *Multipart) *AT {
+	if m == nil {
+		at.setErr(fmt.Errorf("nil multipart"))
+		return at
+	}
+	if m.err != nil {
+		at.setErr(m.err)
+		return at
+	}
+
+	at.multipart = m
+	return at
+}
+
+// writeTo 写入请求体，返回带boundary的Content-Type
+func (m *Multipart) writeTo(w io.Writer, param any) (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+
+	mw := multipart.NewWriter(w)
+	fields, err := paramFields(param)
+	if err != nil {
+		return "", err
+	}
+	for _, field := range fields {
+		if err := mw.WriteField(field.name, field.value); err != nil {
+			return "", err
+		}
+	}
+
+	for _, part := range m.parts {
+		if !part.isFile {
+			if err := mw.WriteField(part.name, part.value); err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		data, err := part.content()
+		if err != nil {
+			return "", err
+		}
+
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{
+			"name":     part.name,
+			"filename": part.fileName,
+		}))
+		h.Set("Content-Type", part.contentType)
+		pw, err := mw.CreatePart(h)
+		if err != nil {
+			return "", err
+		}
+		if _, err := pw.Write(data); err != nil {
+			return "", err
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		return "", err
+	}
+	return mw.FormDataContentType(), nil
+}
+
+func (p *multipartPart) content() ([]byte, error) {
+	if p.path == "" {
+		return p.data, nil
+	}
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("read part %s failed: %w", p.name, err)
+	}
+	return data, nil
+}
+
+// block 文档里的参数部分，AT的参数按结构体列出字段，其余部分逐个列出
+func (m *Multipart) block(param any) (string, map[string]string, error) {
+	var block string
+	var kcm map[string]string
+	if param != nil {
+		var err error
+		block, kcm, err = structToBlock(paramName, paramInMultipart, "", param)
+		if err != nil {
+			return "", nil, err
+		}
+		block = strings.TrimSuffix(block, "\n")
+	} else {
+		block = paramName + " - " + paramInMultipart + "\n\n"
+	}
+
+	for _, part := range m.parts {
+		if part.isFile {
+			block += fmt.Sprintf("* %s (*file*, %s) %s\n", part.name, part.contentType, part.fileName)
+		} else {
+			block += fmt.Sprintf("* %s (*string*)\n", part.name)
+		}
+	}
+	block += "\n"
+
+	return block, kcm, nil
+}
+
+// summary 文档里的参数示例，文件只列出文件名、类型和大小
+func (m *Multipart) summary(param any) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	fields, err := paramFields(param)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range fields {
+		fmt.Fprintf(buf, "%s: %s\n", field.name, field.value)
+	}
+	for _, part := range m.parts {
+		if !part.isFile {
+			fmt.Fprintf(buf, "%s: %s\n", part.name, part.value)
+			continue
+		}
+		data, err := part.content()
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(buf, "%s: @%s (%s, %d bytes)\n", part.name, part.fileName, part.contentType, len(data))
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// curlArgs 转为curl的参数：文本字段使用--form-string，以免以@或<开头的值被当作文件；
+// 文件使用-F，如：users=@a.csv;type=text/csv，内存里的文件只能给出文件名
+func (m *Multipart) curlArgs(param any) ([]string, error) {
+	fields, err := paramFields(param)
+	if err != nil {
+		return nil, err
+	}
+	r := make([]string, 0, 2*(len(fields)+len(m.parts)))
+	for _, field := range fields {
+		r = append(r, "--form-string", shellQuote(field.name+"="+field.value))
+	}
+	for _, part := range m.parts {
+		if !part.isFile {
+			r = append(r, "--form-string", shellQuote(part.name+"="+part.value))
+			continue
+		}
+		file := part.path
+		if file == "" {
+			file = part.fileName
+		}
+		r = append(r, "-F", shellQuote(part.name+"=@"+file+";type="+part.contentType))
+	}
+	return r, nil
+}
+
+type formField struct {
+	name, value string
+}
+
+// paramFields 将参数转为按键排序的字段，编码规则与表单格式相同，如：嵌套结构体的键为a.b，结构体切片的键为list[0].name
+func paramFields(param any) ([]formField, error) {
+	if param == nil {
+		return nil, nil
+	}
+	values, err := formValues(param)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var fields []formField
+	for _, key := range keys {
+		for _, value := range values[key] {
+			fields = append(fields, formField{name: key, value: value})
+		}
+	}
+	return fields, nil
+}
+
+func fileContentType(name, contentType string) string {
+	if contentType != "" {
+		return contentType
+	}
+	if ct := mime.TypeByExtension(filepath.Ext(name)); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
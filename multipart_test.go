@@ -0,0 +1,185 @@
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type (
+	importParam struct {
+		Source string   `json:"source"` // 来源
+		Tags   []string `json:"tags"`   // 标签
+	}
+	importPart struct {
+		FileName    string `json:"fileName"`
+		ContentType string `json:"contentType"`
+		Content     string `json:"content"`
+	}
+	importResult struct {
+		Fields map[string][]string    `json:"fields"`
+		Files  map[string]*importPart `json:"files"`
+	}
+)
+
+func multipartMux() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/import", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result := importResult{Fields: r.MultipartForm.Value, Files: make(map[string]*importPart)}
+		for name, headers := range r.MultipartForm.File {
+			f, err := headers[0].Open()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			data, _ := io.ReadAll(f)
+			f.Close()
+			result.Files[name] = &importPart{
+				FileName:    headers[0].Filename,
+				ContentType: headers[0].Header.Get("Content-Type"),
+				Content:     string(data),
+			}
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(result)
+	})
+	return mux
+}
+
+func TestMultipart(t *testing.T) {
+	var r importResult
+	at := NewAT("/import", http.MethodPost, "导入用户", nil, nil).
+		SetHandler(multipartMux()).
+		SetParam(&importParam{Source: "excel", Tags: []string{"a", "b"}}).
+		SetMultipart(NewMultipart().
+			Field("remark", "first").
+			File("users", "testdata/upload/users.csv", "").
+			Reader("avatar", "avatar.png", "image/png", strings.NewReader("png"))).
+		Run().
+		EqualCode(http.StatusOK).
+		Result(&r)
+	if err := at.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := r.Fields["tags"]; len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Fatalf("bad tags: %v", got)
+	}
+	if got := r.Fields["source"]; len(got) != 1 || got[0] != "excel" {
+		t.Fatalf("bad source: %v", got)
+	}
+	if got := r.Fields["remark"]; len(got) != 1 || got[0] != "first" {
+		t.Fatalf("bad remark: %v", got)
+	}
+	users := r.Files["users"]
+	if users == nil || users.FileName != "users.csv" || !strings.HasPrefix(users.ContentType, "text/csv") || users.Content != "id,name\n1,jd\n" {
+		t.Fatalf("bad users: %+v", users)
+	}
+	avatar := r.Files["avatar"]
+	if avatar == nil || avatar.FileName != "avatar.png" || avatar.ContentType != "image/png" || avatar.Content != "png" {
+		t.Fatalf("bad avatar: %+v", avatar)
+	}
+
+	curl := at.Curl()
+	for _, want := range []string{
+		"--form-string 'source=excel' --form-string 'tags=a' --form-string 'tags=b' --form-string 'remark=first'",
+		"-F 'users=@testdata/upload/users.csv;type=text/csv",
+		"-F 'avatar=@avatar.png;type=image/png'",
+	} {
+		if !strings.Contains(curl, want) {
+			t.Fatalf("curl should contain %q, have %s", want, curl)
+		}
+	}
+	if strings.Contains(curl, "Content-Type") {
+		t.Fatalf("curl should not contain Content-Type: %s", curl)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := at.WriteFile(buf).Err(); err != nil {
+		t.Fatal(err)
+	}
+	doc := buf.String()
+	for _, want := range []string{
+		"Param - Multipart",
+		"* tags (*string list*) \n* remark (*string*)\n",
+		"* users (*file*, text/csv; charset=utf-8) users.csv\n",
+		"* avatar (*file*, image/png) avatar.png\n",
+		"avatar: @avatar.png (image/png, 3 bytes)",
+	} {
+		if !strings.Contains(doc, want) {
+			t.Fatalf("doc should contain %q, have:\n%s", want, doc)
+		}
+	}
+}
+
+func TestMultipartError(t *testing.T) {
+	err := NewAT("/import", http.MethodPost, "导入用户", nil, nil).
+		SetMultipart(NewMultipart().File("users", "", "")).
+		Err()
+	if err == nil || !strings.Contains(err.Error(), "empty file for part users") {
+		t.Fatalf("bad error: %v", err)
+	}
+
+	err = NewAT("/import", http.MethodPost, "导入用户", nil, nil).
+		SetHandler(multipartMux()).
+		SetMultipart(NewMultipart().File("users", "testdata/upload/none.csv", "")).
+		Run().
+		Err()
+	if err == nil || !strings.Contains(err.Error(), "read part users failed") {
+		t.Fatalf("bad error: %v", err)
+	}
+}
+
+func TestMultipartNestedParam(t *testing.T) {
+	type (
+		owner struct {
+			Name string `json:"name"`
+		}
+		item struct {
+			Id int `json:"id"`
+		}
+		param struct {
+			Source string `json:"source"`
+			Owner  owner  `json:"owner"`
+			Items  []item `json:"items"`
+		}
+	)
+
+	var r importResult
+	at := NewAT("/import", http.MethodPost, "导入用户", nil, nil).
+		SetHandler(multipartMux()).
+		SetParam(&param{Source: "excel", Owner: owner{Name: "jd"}, Items: []item{{Id: 1}, {Id: 2}}}).
+		SetMultipart(NewMultipart().Field("remark", "first")).
+		Run().
+		EqualCode(http.StatusOK).
+		Result(&r)
+	if err := at.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]string{
+		"source":      "excel",
+		"owner.name":  "jd",
+		"items[0].id": "1",
+		"items[1].id": "2",
+		"remark":      "first",
+	} {
+		if got := r.Fields[key]; len(got) != 1 || got[0] != want {
+			t.Errorf("bad field %s: %v", key, got)
+		}
+	}
+	if got := r.Fields["owner"]; got != nil {
+		t.Errorf("nested struct should not be sent as a whole: %v", got)
+	}
+
+	if curl := at.Curl(); !strings.Contains(curl, "--form-string 'items[0].id=1' --form-string 'items[1].id=2' --form-string 'owner.name=jd'") {
+		t.Errorf("bad curl: %s", curl)
+	}
+}
@@ -0,0 +1,724 @@
+package apitest
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/donnol/do"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	openAPIVersion        = "3.1.0"
+	openAPIDefaultVersion = "1.0.0"
+)
+
+type (
+	// OpenAPI OpenAPI 3.1文档
+	OpenAPI struct {
+		OpenAPI    string                      `json:"openapi"`
+		Info       OpenAPIInfo                 `json:"info"`
+		Servers    []OpenAPIServer             `json:"servers,omitempty"`
+		Paths      map[string]*OpenAPIPathItem `json:"paths"`
+		Components *OpenAPIComponents          `json:"components,omitempty"`
+
+		schemas *schemaBuilder // 所有接口共用，以便区分不同包的同名类型
+	}
+
+	OpenAPIInfo struct {
+		Title       string `json:"title"`
+		Description string `json:"description,omitempty"`
+		Version     string `json:"version"`
+	}
+
+	OpenAPIServer struct {
+		URL         string `json:"url"`
+		Description string `json:"description,omitempty"`
+	}
+
+	// OpenAPIPathItem 路径下的接口，Parameters为路径下所有接口共用的参数
+	OpenAPIPathItem struct {
+		Summary     string              `json:"summary,omitempty"`
+		Description string              `json:"description,omitempty"`
+		Get         *OpenAPIOperation   `json:"get,omitempty"`
+		Put         *OpenAPIOperation   `json:"put,omitempty"`
+		Post        *OpenAPIOperation   `json:"post,omitempty"`
+		Delete      *OpenAPIOperation   `json:"delete,omitempty"`
+		Options     *OpenAPIOperation   `json:"options,omitempty"`
+		Head        *OpenAPIOperation   `json:"head,omitempty"`
+		Patch       *OpenAPIOperation   `json:"patch,omitempty"`
+		Trace       *OpenAPIOperation   `json:"trace,omitempty"`
+		Servers     []OpenAPIServer     `json:"servers,omitempty"`
+		Parameters  []*OpenAPIParameter `json:"parameters,omitempty"`
+	}
+
+	OpenAPIOperation struct {
+		OperationID string                      `json:"operationId,omitempty"`
+		Summary     string                      `json:"summary,omitempty"`
+		Description string                      `json:"description,omitempty"`
+		Tags        []string                    `json:"tags,omitempty"`
+		Parameters  []*OpenAPIParameter         `json:"parameters,omitempty"`
+		RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+		Responses   map[string]*OpenAPIResponse `json:"responses"`
+		Security    []map[string][]string       `json:"security,omitempty"`
+		Status      string                      `json:"x-status,omitempty"` // 接口实现情况
+		Errors      []any                       `json:"x-errors,omitempty"` // 错误码
+	}
+
+	OpenAPIParameter struct {
+		Name        string         `json:"name"`
+		In          string         `json:"in"` // query, path, header, cookie
+		Description string         `json:"description,omitempty"`
+		Required    bool           `json:"required,omitempty"`
+		Schema      *OpenAPISchema `json:"schema,omitempty"`
+	}
+
+	OpenAPIRequestBody struct {
+		Description string                       `json:"description,omitempty"`
+		Required    bool                         `json:"required,omitempty"`
+		Content     map[string]*OpenAPIMediaType `json:"content"`
+	}
+
+	OpenAPIResponse struct {
+		Description string                       `json:"description"`
+		Content     map[string]*OpenAPIMediaType `json:"content,omitempty"`
+	}
+
+	OpenAPIMediaType struct {
+		Schema  *OpenAPISchema `json:"schema,omitempty"`
+		Example any            `json:"example,omitempty"`
+	}
+
+	OpenAPISchema struct {
+		Ref                  string                    `json:"$ref,omitempty"`
+		Type                 string                    `json:"type,omitempty"`
+		Format               string                    `json:"format,omitempty"`
+		Description          string                    `json:"description,omitempty"`
+		Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+		Required             []string                  `json:"required,omitempty"`
+		Items                *OpenAPISchema            `json:"items,omitempty"`
+		AdditionalProperties *OpenAPISchema            `json:"additionalProperties,omitempty"`
+		Enum                 []any                     `json:"enum,omitempty"`
+		AllOf                []*OpenAPISchema          `json:"allOf,omitempty"`
+	}
+
+	OpenAPIComponents struct {
+		Schemas         map[string]*OpenAPISchema         `json:"schemas,omitempty"`
+		SecuritySchemes map[string]*OpenAPISecurityScheme `json:"securitySchemes,omitempty"`
+	}
+
+	OpenAPISecurityScheme struct {
+		Type        string `json:"type"` // apiKey, http
+		Name        string `json:"name,omitempty"`
+		In          string `json:"in,omitempty"`
+		Description string `json:"description,omitempty"`
+	}
+)
+
+// NewOpenAPI 新建OpenAPI文档
+func NewOpenAPI(title string) *OpenAPI {
+	return &OpenAPI{
+		OpenAPI: openAPIVersion,
+		Info: OpenAPIInfo{
+			Title:   title,
+			Version: openAPIDefaultVersion,
+		},
+		Paths: make(map[string]*OpenAPIPathItem),
+	}
+}
+
+// Add 添加接口，相同路径和方法的接口会被覆盖
+func (doc *OpenAPI) Add(at *AT) error {
+	if doc.schemas == nil {
+		doc.schemas = newSchemaBuilder()
+	}
+	b := doc.schemas
+	op, err := at.openAPIOperation(b)
+	if err != nil {
+		return err
+	}
+	if len(b.components) > 0 {
+		if doc.Components == nil {
+			doc.Components = &OpenAPIComponents{}
+		}
+		if doc.Components.Schemas == nil {
+			doc.Components.Schemas = make(map[string]*OpenAPISchema)
+		}
+		for name, schema := range b.components {
+			doc.Components.Schemas[name] = schema
+		}
+	}
+
+	// 参数里与路径参数同名的字段，作为路径参数的类型
+	path, pathParams := openAPIPath(at.path)
+	for _, pp := range pathParams {
+		for i, p := range op.Parameters {
+			if p.In == "query" && p.Name == pp.Name {
+				pp.Description, pp.Schema = p.Description, p.Schema
+				op.Parameters = append(op.Parameters[:i], op.Parameters[i+1:]...)
+				break
+			}
+		}
+	}
+	op.Parameters = append(pathParams, op.Parameters...)
+
+	item, ok := doc.Paths[path]
+	if !ok {
+		item = &OpenAPIPathItem{}
+		doc.Paths[path] = item
+	}
+	item.SetOperation(at.method, op)
+
+	if at.authHeaderKey != "" {
+		if doc.Components == nil {
+			doc.Components = &OpenAPIComponents{}
+		}
+		if doc.Components.SecuritySchemes == nil {
+			doc.Components.SecuritySchemes = make(map[string]*OpenAPISecurityScheme)
+		}
+		name := http.CanonicalHeaderKey(at.authHeaderKey)
+		doc.Components.SecuritySchemes[name] = &OpenAPISecurityScheme{
+			Type:        "apiKey",
+			Name:        name,
+			In:          "header",
+			Description: at.authHeaderValue,
+		}
+	}
+
+	return nil
+}
+
+// operation 请求方法对应的字段
+func (item *OpenAPIPathItem) operation(method string) **OpenAPIOperation {
+	switch strings.ToUpper(method) {
+	case http.MethodGet:
+		return &item.Get
+	case http.MethodPut:
+		return &item.Put
+	case http.MethodPost:
+		return &item.Post
+	case http.MethodDelete:
+		return &item.Delete
+	case http.MethodOptions:
+		return &item.Options
+	case http.MethodHead:
+		return &item.Head
+	case http.MethodPatch:
+		return &item.Patch
+	case http.MethodTrace:
+		return &item.Trace
+	}
+	return nil
+}
+
+// Operation 获取请求方法对应的接口，没有时返回nil
+func (item *OpenAPIPathItem) Operation(method string) *OpenAPIOperation {
+	if op := item.operation(method); op != nil {
+		return *op
+	}
+	return nil
+}
+
+// SetOperation 设置请求方法对应的接口，不支持的方法忽略
+func (item *OpenAPIPathItem) SetOperation(method string, op *OpenAPIOperation) {
+	if p := item.operation(method); p != nil {
+		*p = op
+	}
+}
+
+// WriteTo 以json格式写入
+func (doc *OpenAPI) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.MarshalIndent(doc, "", "    ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(append(data, '\n'))
+	return int64(n), err
+}
+
+// WriteOpenAPI 写入当前接口的OpenAPI文档
+func (at *AT) WriteOpenAPI(w io.Writer) *AT {
+	if w == nil {
+		at.setErr(fmt.Errorf("nil writer"))
+		return at
+	}
+
+	doc := NewOpenAPI(at.comment)
+	if err := doc.Add(at); err != nil {
+		at.setErr(err)
+		return at
+	}
+	if _, err := doc.WriteTo(w); err != nil {
+		at.setErr(err)
+		return at
+	}
+
+	return at
+}
+
+// MakeOpenAPI 与MakeDoc一样收集接口，生成一份OpenAPI文档
+func MakeOpenAPI(t DocHelper, dir, file, title, pathPrefix string) {
+	doc := NewOpenAPI(title)
+	for _, item := range t.FindTestAPIsByPrefix(pathPrefix) {
+		at := item
+		p, r := at.GetParamResult(t.GetParamResult)
+		if err := at.SetParam(p).
+			FakeRun().
+			Result(r).
+			Err(); err != nil {
+			t.Fatal(err)
+		}
+		if err := doc.Add(at.AT); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, file), os.O_CREATE|os.O_TRUNC|os.O_RDWR, os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := doc.WriteTo(f); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// openAPIOperation 使用与makeDoc相同的信息生成接口描述，递归类型的schema放在b.components里
+func (at *AT) openAPIOperation(b *schemaBuilder) (*OpenAPIOperation, error) {
+	op := &OpenAPIOperation{
+		OperationID: openAPIOperationID(at.method, at.path),
+		Summary:     at.comment,
+		Responses:   make(map[string]*OpenAPIResponse),
+	}
+	if at.status != 0 {
+		op.Status = at.status.String()
+	}
+
+	// 参数
+	var schema *OpenAPISchema
+	if at.param != nil {
+		var err error
+		schema, err = b.dataSchema(at.param)
+		if err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case at.param != nil && at.paramPosition() == paramInQuery:
+		op.Parameters = append(op.Parameters, schemaToParameters(b.resolve(schema), "query")...)
+	case at.file != "" || at.multipart != nil:
+		if schema == nil {
+			schema = &OpenAPISchema{Type: "object"}
+		}
+		if schema.Properties == nil {
+			schema.Properties = make(map[string]*OpenAPISchema)
+		}
+		if at.file != "" {
+			schema.Properties["file"] = &OpenAPISchema{Type: "string", Format: "binary"}
+		}
+		if at.multipart != nil {
+			for _, part := range at.multipart.parts {
+				if part.isFile {
+					schema.Properties[part.name] = &OpenAPISchema{Type: "string", Format: "binary", Description: part.contentType}
+				} else {
+					schema.Properties[part.name] = &OpenAPISchema{Type: "string"}
+				}
+			}
+		}
+		op.RequestBody = &OpenAPIRequestBody{
+			Required: true,
+			Content: map[string]*OpenAPIMediaType{
+				"multipart/form-data": {Schema: schema},
+			},
+		}
+	case at.param != nil:
+		example, err := formatExample(at.paramFormat, at.param)
+		if err != nil {
+			return nil, err
+		}
+		op.RequestBody = &OpenAPIRequestBody{
+			Required: true,
+			Content: map[string]*OpenAPIMediaType{
+				contentTypeOf(at.paramFormat): {Schema: schema, Example: example},
+			},
+		}
+	}
+
+	// 认证
+	if at.authHeaderKey != "" {
+		op.Security = append(op.Security, map[string][]string{
+			http.CanonicalHeaderKey(at.authHeaderKey): {},
+		})
+	}
+
+	// 返回
+	resp := &OpenAPIResponse{Description: http.StatusText(http.StatusOK)}
+	if at.result != nil {
+		schema, err := b.dataSchema(at.result)
+		if err != nil {
+			return nil, err
+		}
+		example, err := at.resultExample()
+		if err != nil {
+			return nil, err
+		}
+		resp.Content = map[string]*OpenAPIMediaType{
+			contentTypeOf(at.resultFormat): {Schema: schema, Example: example},
+		}
+	}
+	code := http.StatusOK
+	if at.resp != nil {
+		code = at.resp.StatusCode
+		resp.Description = http.StatusText(code)
+	}
+	op.Responses[fmt.Sprint(code)] = resp
+
+	// 错误码
+	if len(at.ates) > 0 {
+		var lines []string
+		for _, e := range at.ates {
+			if v, ok := e.(APIError); ok {
+				op.Errors = append(op.Errors, map[string]string{"code": v.Code(), "msg": v.Msg()})
+				lines = append(lines, fmt.Sprintf("* `%s` %s", v.Code(), v.Msg()))
+				continue
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				return nil, fmt.Errorf("json marshal error '%v' failed: %v", e, err)
+			}
+			op.Errors = append(op.Errors, json.RawMessage(data))
+			lines = append(lines, "* "+string(data))
+		}
+		op.Description = errorName + "\n\n" + strings.Join(lines, "\n")
+	}
+
+	return op, nil
+}
+
+// resultExample 优先使用真实的响应作为示例；protobuf使用解析后的结果
+func (at *AT) resultExample() (any, error) {
+	if at.resp == nil || at.resultFormat == protobufFormat {
+		return formatExample(at.resultFormat, at.result)
+	}
+
+	data, _, err := copyResponseBody(at.resp)
+	if err != nil {
+		return nil, err
+	}
+	if at.resultFormat == "xml" {
+		return string(data), nil
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return string(data), nil
+	}
+	return v, nil
+}
+
+// formatExample json格式直接使用值本身，xml格式使用序列化后的字符串，protobuf使用protojson
+func formatExample(format string, v any) (any, error) {
+	switch format {
+	case "xml":
+		data, err := xml.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	case protobufFormat:
+		data, err := protoJSON(v)
+		if err != nil {
+			return nil, err
+		}
+		var example any
+		if err := json.Unmarshal(data, &example); err != nil {
+			return nil, err
+		}
+		return example, nil
+	}
+	return v, nil
+}
+
+func contentTypeOf(format string) string {
+	if !isBuiltinParamFormat(format) {
+		if contentType := paramContentType(format); contentType != "" {
+			if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+				return mediaType
+			}
+			return contentType
+		}
+	}
+
+	switch format {
+	case "xml":
+		return "application/xml"
+	case formParamFormat:
+		return formContentType
+	default:
+		return "application/json"
+	}
+}
+
+// openAPIPath 将gin风格的路径参数转为OpenAPI的形式，如：/book/:id -> /book/{id}
+func openAPIPath(path string) (string, []*OpenAPIParameter) {
+	if i := strings.Index(path, "?"); i != -1 {
+		path = path[:i]
+	}
+
+	var params []*OpenAPIParameter
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if part == "" || (part[0] != ':' && part[0] != '*') {
+			continue
+		}
+		name := part[1:]
+		parts[i] = "{" + name + "}"
+		params = append(params, &OpenAPIParameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   &OpenAPISchema{Type: "string"},
+		})
+	}
+
+	return strings.Join(parts, "/"), params
+}
+
+// openAPIOperationID 如：GET /book/:id -> getBookId
+func openAPIOperationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) {
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return id
+}
+
+func schemaToParameters(schema *OpenAPISchema, in string) []*OpenAPIParameter {
+	required := make(map[string]bool)
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	params := make([]*OpenAPIParameter, 0, len(names))
+	for _, name := range names {
+		prop := schema.Properties[name]
+		params = append(params, &OpenAPIParameter{
+			Name:        name,
+			In:          in,
+			Description: prop.Description,
+			Required:    required[name],
+			Schema:      prop,
+		})
+	}
+	return params
+}
+
+// schemaBuilder 生成schema，引用自身的结构体定义在components.schemas里，使用$ref引用
+type schemaBuilder struct {
+	components map[string]*OpenAPISchema
+	names      map[reflect.Type]string
+	visiting   map[reflect.Type]bool
+	recursive  map[reflect.Type]bool
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{
+		components: make(map[string]*OpenAPISchema),
+		names:      make(map[reflect.Type]string),
+		visiting:   make(map[reflect.Type]bool),
+		recursive:  make(map[reflect.Type]bool),
+	}
+}
+
+// ref 类型在components.schemas里的引用，不同包的同名类型加上包名区分
+func (b *schemaBuilder) ref(typ reflect.Type) *OpenAPISchema {
+	name, ok := b.names[typ]
+	if !ok {
+		name = typ.Name()
+		for other, otherName := range b.names {
+			if otherName == name && other != typ {
+				pkg := typ.PkgPath()
+				name = goName(pkg[strings.LastIndex(pkg, "/")+1:]) + name
+				break
+			}
+		}
+		b.names[typ] = name
+	}
+	return &OpenAPISchema{Ref: componentSchemaPrefix + name}
+}
+
+// resolve 引用components.schemas的schema，返回被引用的schema
+func (b *schemaBuilder) resolve(schema *OpenAPISchema) *OpenAPISchema {
+	if schema.Ref == "" {
+		return schema
+	}
+	if target := b.components[strings.TrimPrefix(schema.Ref, componentSchemaPrefix)]; target != nil {
+		return target
+	}
+	return schema
+}
+
+// dataSchema 与structToBlock一样使用do.ResolveStruct解析结构体，得到带注释的schema
+func (b *schemaBuilder) dataSchema(data any) (*OpenAPISchema, error) {
+	if m, ok := data.(proto.Message); ok {
+		return protoSchema(m.ProtoReflect().Descriptor(), nil), nil
+	}
+
+	refv := reflect.ValueOf(data)
+	if !refv.IsValid() {
+		return &OpenAPISchema{}, nil
+	}
+	typ := refv.Type()
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	switch typ.Kind() {
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Struct {
+			datastructs, err := do.ResolveStructSlice(data)
+			if err != nil {
+				return nil, err
+			}
+			var s do.Struct
+			if len(datastructs) > 0 {
+				s = datastructs[0]
+			}
+			return &OpenAPISchema{Type: "array", Items: b.typeSchema(typ.Elem(), s.Fields)}, nil
+		}
+	case reflect.Struct:
+		s, err := do.ResolveStruct(data)
+		if err != nil {
+			return nil, err
+		}
+		return b.typeSchema(typ, s.Fields), nil
+	}
+
+	return b.typeSchema(typ, nil), nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// typeSchema fields为do.ResolveStruct解析得到的字段，用于获取注释和接口字段的实际类型
+func (b *schemaBuilder) typeSchema(typ reflect.Type, fields []do.Field) *OpenAPISchema {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	if typ == timeType {
+		return &OpenAPISchema{Type: "string", Format: "date-time"}
+	}
+	if replaceTypeName(typ) != "" {
+		return &OpenAPISchema{Type: replaceTypeName(typ)}
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		return &OpenAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &OpenAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &OpenAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &OpenAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &OpenAPISchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &OpenAPISchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return &OpenAPISchema{Type: "string", Format: "byte"}
+		}
+		return &OpenAPISchema{Type: "array", Items: b.typeSchema(typ.Elem(), fields)}
+	case reflect.Map:
+		return &OpenAPISchema{Type: "object", AdditionalProperties: b.typeSchema(typ.Elem(), fields)}
+	case reflect.Interface:
+		if len(fields) == 0 {
+			return &OpenAPISchema{}
+		}
+		schema := &OpenAPISchema{Type: "object"}
+		b.fieldsToSchema(schema, fields)
+		return schema
+	case reflect.Struct:
+		// 正在生成的类型再次出现，说明引用了自身
+		if b.visiting[typ] {
+			b.recursive[typ] = true
+			return b.ref(typ)
+		}
+		b.visiting[typ] = true
+		defer delete(b.visiting, typ)
+
+		schema := &OpenAPISchema{Type: "object"}
+		if len(fields) == 0 {
+			for i := 0; i < typ.NumField(); i++ {
+				fields = append(fields, do.Field{StructField: typ.Field(i)})
+			}
+		}
+		b.fieldsToSchema(schema, fields)
+		if b.recursive[typ] {
+			ref := b.ref(typ)
+			b.components[strings.TrimPrefix(ref.Ref, componentSchemaPrefix)] = schema
+			return ref
+		}
+		return schema
+	}
+
+	return &OpenAPISchema{}
+}
+
+// fieldsToSchema 与fieldsToLine的字段处理一致：内嵌结构体的字段提升到当前层级
+func (b *schemaBuilder) fieldsToSchema(schema *OpenAPISchema, fields []do.Field) {
+	for _, field := range fields {
+		df, ok := resolveDocField(field.StructField)
+		if !ok {
+			continue
+		}
+
+		if df.embed {
+			// 递归类型得到的是引用，需要展开被引用的schema
+			inner := b.resolve(b.typeSchema(field.StructField.Type, field.Struct.Fields))
+			if schema.Properties == nil {
+				schema.Properties = make(map[string]*OpenAPISchema)
+			}
+			for k, v := range inner.Properties {
+				schema.Properties[k] = v
+			}
+			schema.Required = append(schema.Required, inner.Required...)
+			continue
+		}
+		fieldName := df.name
+
+		var prop *OpenAPISchema
+		if df.hasOpt("string") {
+			prop = &OpenAPISchema{Type: "string"}
+		} else {
+			prop = b.typeSchema(field.StructField.Type, field.Struct.Fields)
+		}
+		prop.Description = field.Comment
+
+		if schema.Properties == nil {
+			schema.Properties = make(map[string]*OpenAPISchema)
+		}
+		schema.Properties[fieldName] = prop
+		if !df.hasOpt("omitempty") && field.StructField.Type.Kind() != reflect.Pointer {
+			schema.Required = append(schema.Required, fieldName)
+		}
+	}
+}
@@ -0,0 +1,692 @@
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ReadOpenAPI 读取OpenAPI 3文档，支持json和yaml格式
+func ReadOpenAPI(file string) (*OpenAPI, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw any
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("yaml decode %s failed: %w", file, err)
+		}
+		raw = normalizeYAML(raw)
+	default:
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("json decode %s failed: %w", file, err)
+		}
+	}
+	normalizeSchemaType(raw)
+
+	// 通过json转为OpenAPI结构
+	data, err = json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var doc OpenAPI
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("decode openapi %s failed: %w", file, err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("not support openapi version %q", doc.OpenAPI)
+	}
+
+	return &doc, nil
+}
+
+// normalizeYAML yaml.v2解析出的map键为any，转为string
+func normalizeYAML(v any) any {
+	switch vv := v.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(vv))
+		for k, item := range vv {
+			m[fmt.Sprint(k)] = normalizeYAML(item)
+		}
+		return m
+	case []any:
+		for i, item := range vv {
+			vv[i] = normalizeYAML(item)
+		}
+	}
+	return v
+}
+
+// normalizeSchemaType OpenAPI 3.1里type可以是数组，如["string", "null"]，取第一个非null的类型
+func normalizeSchemaType(v any) {
+	switch vv := v.(type) {
+	case map[string]any:
+		if types, ok := vv["type"].([]any); ok {
+			vv["type"] = ""
+			for _, t := range types {
+				if s, ok := t.(string); ok && s != "null" {
+					vv["type"] = s
+					break
+				}
+			}
+		}
+		for _, item := range vv {
+			normalizeSchemaType(item)
+		}
+	case []any:
+		for _, item := range vv {
+			normalizeSchemaType(item)
+		}
+	}
+}
+
+// GenTestFromOpenAPI 根据OpenAPI文档生成测试代码：每个接口生成参数、结果结构体和使用NewAT的测试函数
+func GenTestFromOpenAPI(doc *OpenAPI, pkg string, w io.Writer) error {
+	g := &testGenerator{
+		doc:      doc,
+		defined:  make(map[string]bool),
+		defining: make(map[string]bool),
+		comments: make(map[string]string),
+		imports:  map[string]bool{"net/http": true, "testing": true, "github.com/donnol/apitest": true},
+	}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var tests bytes.Buffer
+	for _, path := range paths {
+		item := doc.Paths[path]
+		if item == nil {
+			continue
+		}
+		for _, method := range []string{
+			http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+			http.MethodHead, http.MethodOptions, http.MethodTrace,
+		} {
+			op := item.Operation(method)
+			if op == nil {
+				continue
+			}
+			if err := g.genTest(&tests, path, method, mergePathItem(item, op)); err != nil {
+				return fmt.Errorf("gen test for %s %s failed: %w", method, path, err)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("// 根据OpenAPI文档")
+	if doc.Info.Title != "" {
+		buf.WriteString("《" + doc.Info.Title + "》")
+	}
+	buf.WriteString("生成的测试，请按需补充参数和校验\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+
+	imports := make([]string, 0, len(g.imports))
+	for imp := range g.imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+	// 标准库在前，第三方库在后
+	buf.WriteString("import (\n")
+	for _, std := range []bool{true, false} {
+		for _, imp := range imports {
+			if !strings.Contains(imp, ".") == std {
+				fmt.Fprintf(&buf, "%q\n", imp)
+			}
+		}
+		if std {
+			buf.WriteString("\n")
+		}
+	}
+	buf.WriteString(")\n\n")
+
+	for _, typ := range g.types {
+		buf.WriteString(typ + "\n")
+	}
+	buf.Write(tests.Bytes())
+
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("format code failed: %w\n%s", err, buf.Bytes())
+	}
+	_, err = w.Write(code)
+	return err
+}
+
+// mergePathItem 将路径级别的参数合并到接口里，接口里同名同位置的参数优先；接口没有summary时使用路径的
+func mergePathItem(item *OpenAPIPathItem, op *OpenAPIOperation) *OpenAPIOperation {
+	merged := *op
+	if merged.Summary == "" {
+		merged.Summary = item.Summary
+	}
+	if len(item.Parameters) == 0 {
+		return &merged
+	}
+
+	defined := make(map[string]bool, len(op.Parameters))
+	for _, p := range op.Parameters {
+		if p != nil {
+			defined[p.In+" "+p.Name] = true
+		}
+	}
+	merged.Parameters = nil
+	for _, p := range item.Parameters {
+		if p != nil && !defined[p.In+" "+p.Name] {
+			merged.Parameters = append(merged.Parameters, p)
+		}
+	}
+	merged.Parameters = append(merged.Parameters, op.Parameters...)
+	return &merged
+}
+
+type testGenerator struct {
+	doc      *OpenAPI
+	types    []string
+	defined  map[string]bool
+	defining map[string]bool
+	comments map[string]string // schema没有描述时使用的类型注释
+	imports  map[string]bool
+}
+
+func (g *testGenerator) genTest(w io.Writer, path, method string, op *OpenAPIOperation) error {
+	id := op.OperationID
+	if id == "" {
+		id = openAPIOperationID(method, path)
+	}
+	name := goName(id)
+
+	comment := op.Summary
+	if comment == "" {
+		comment = name
+	}
+	g.comments[name+"Param"] = comment + "的参数"
+	g.comments[name+"Result"] = comment + "的结果"
+
+	// 参数：路径参数、查询参数和请求体合并为一个结构体
+	paramType, paramFormat, err := g.paramType(name+"Param", op)
+	if err != nil {
+		return err
+	}
+
+	// 结果：取第一个2xx响应
+	resultType, resultFormat, err := g.resultType(name+"Result", op)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "// Test%s %s\n", name, oneLine(comment))
+	fmt.Fprintf(w, "func Test%s(t *testing.T) {\n", name)
+	if resultType != "" {
+		fmt.Fprintf(w, "var r %s\n", resultType)
+	}
+	fmt.Fprintf(w, "if err := apitest.NewAT(%q, %s, %q, nil, nil).\n", ginPath(path), httpMethodConst(method), comment)
+	for _, name := range g.securityHeaders(op) {
+		fmt.Fprintf(w, "MarkAuthHeader(%q, \"\").\n", name)
+	}
+	switch paramFormat {
+	case "xml":
+		fmt.Fprintf(w, "UseXMLParamFormat().\n")
+	case formParamFormat:
+		fmt.Fprintf(w, "UseFormParamFormat().\n")
+	}
+	if resultFormat == "xml" {
+		fmt.Fprintf(w, "UseXMLResultFormat().\n")
+	}
+	switch {
+	case g.defined[paramType]:
+		fmt.Fprintf(w, "SetParam(&%s{}).\n", paramType)
+	case paramType != "":
+		fmt.Fprintf(w, "SetParam(new(%s)).\n", paramType)
+	}
+	fmt.Fprintf(w, "FakeRun().\n")
+	if resultType != "" {
+		fmt.Fprintf(w, "Result(&r).\n")
+	}
+	fmt.Fprintf(w, "Err(); err != nil {\nt.Fatal(err)\n}\n}\n\n")
+
+	return nil
+}
+
+func (g *testGenerator) paramType(name string, op *OpenAPIOperation) (string, string, error) {
+	var fields []genField
+	seen := make(map[string]bool)
+	for _, p := range op.Parameters {
+		if p == nil || (p.In != "query" && p.In != "path") || seen[p.Name] {
+			continue
+		}
+		seen[p.Name] = true
+		schema := p.Schema
+		if schema == nil {
+			schema = &OpenAPISchema{Type: "string"}
+		}
+		typ, err := g.goType(schema, name+goName(p.Name))
+		if err != nil {
+			return "", "", err
+		}
+		fields = append(fields, genField{
+			key:      p.Name,
+			typ:      typ,
+			comment:  p.Description,
+			required: p.Required,
+		})
+	}
+
+	var format string
+	if op.RequestBody != nil {
+		contentType, media := pickMedia(op.RequestBody.Content)
+		if media != nil && media.Schema != nil {
+			format = formatOf(contentType)
+			schema, err := g.resolve(media.Schema)
+			if err != nil {
+				return "", "", err
+			}
+			// 请求体不是对象时，直接使用它的类型作为参数
+			if len(fields) == 0 && len(schema.Properties) == 0 {
+				typ, err := g.goType(media.Schema, name)
+				return typ, format, err
+			}
+			bodyFields, err := g.schemaFields(name, schema)
+			if err != nil {
+				return "", "", err
+			}
+			// 与路径参数和查询参数同名的字段只保留一个
+			for _, f := range bodyFields {
+				if !seen[f.key] {
+					seen[f.key] = true
+					fields = append(fields, f)
+				}
+			}
+		}
+	}
+
+	if len(fields) == 0 {
+		return "", format, nil
+	}
+	g.defineStruct(name, "", fields)
+	return name, format, nil
+}
+
+func (g *testGenerator) resultType(name string, op *OpenAPIOperation) (string, string, error) {
+	codes := make([]string, 0, len(op.Responses))
+	for code := range op.Responses {
+		if strings.HasPrefix(code, "2") {
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) == 0 {
+		if _, ok := op.Responses["default"]; ok {
+			codes = append(codes, "default")
+		}
+	}
+	sort.Strings(codes)
+
+	for _, code := range codes {
+		resp := op.Responses[code]
+		if resp == nil {
+			continue
+		}
+		contentType, media := pickMedia(resp.Content)
+		if media == nil || media.Schema == nil {
+			continue
+		}
+		typ, err := g.goType(media.Schema, name)
+		return typ, formatOf(contentType), err
+	}
+	return "", "", nil
+}
+
+func (g *testGenerator) securityHeaders(op *OpenAPIOperation) []string {
+	if g.doc.Components == nil {
+		return nil
+	}
+	var names []string
+	for _, requirement := range op.Security {
+		for key := range requirement {
+			scheme, ok := g.doc.Components.SecuritySchemes[key]
+			if !ok || scheme == nil {
+				continue
+			}
+			switch {
+			case scheme.Type == "apiKey" && scheme.In == "header":
+				names = append(names, scheme.Name)
+			case scheme.Type == "http":
+				names = append(names, "Authorization")
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+type genField struct {
+	key      string
+	typ      string
+	comment  string
+	required bool
+}
+
+// goType 获取schema对应的Go类型，对象类型会以name定义结构体
+func (g *testGenerator) goType(schema *OpenAPISchema, name string) (string, error) {
+	if schema == nil {
+		return "any", nil
+	}
+	if schema.Ref != "" {
+		refName, err := g.defineRef(schema.Ref)
+		if err != nil {
+			return "", err
+		}
+		if g.defining[refName] { // 自引用
+			return "*" + refName, nil
+		}
+		return refName, nil
+	}
+
+	resolved, err := g.resolve(schema)
+	if err != nil {
+		return "", err
+	}
+	schema = resolved
+
+	switch schema.Type {
+	case "string":
+		switch schema.Format {
+		case "date-time":
+			g.imports["time"] = true
+			return "time.Time", nil
+		case "byte":
+			return "[]byte", nil
+		}
+		return "string", nil
+	case "integer":
+		if schema.Format == "int64" {
+			return "int64", nil
+		}
+		return "int", nil
+	case "number":
+		if schema.Format == "float" {
+			return "float32", nil
+		}
+		return "float64", nil
+	case "boolean":
+		return "bool", nil
+	case "array":
+		typ, err := g.goType(schema.Items, name+"Item")
+		if err != nil {
+			return "", err
+		}
+		return "[]" + typ, nil
+	case "object", "":
+		if len(schema.Properties) > 0 {
+			fields, err := g.schemaFields(name, schema)
+			if err != nil {
+				return "", err
+			}
+			g.defineStruct(name, schema.Description, fields)
+			return name, nil
+		}
+		if schema.AdditionalProperties != nil {
+			typ, err := g.goType(schema.AdditionalProperties, name+"Value")
+			if err != nil {
+				return "", err
+			}
+			return "map[string]" + typ, nil
+		}
+		if schema.Type == "object" {
+			return "map[string]any", nil
+		}
+	}
+	return "any", nil
+}
+
+func (g *testGenerator) schemaFields(name string, schema *OpenAPISchema) ([]genField, error) {
+	required := make(map[string]bool)
+	for _, key := range schema.Required {
+		required[key] = true
+	}
+
+	keys := make([]string, 0, len(schema.Properties))
+	for key := range schema.Properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fields := make([]genField, 0, len(keys))
+	used := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		prop := schema.Properties[key]
+		typeName := name + goName(key)
+		base := typeName
+		for i := 2; used[typeName]; i++ {
+			typeName = fmt.Sprintf("%s%d", base, i)
+		}
+		used[typeName] = true
+		typ, err := g.goType(prop, typeName)
+		if err != nil {
+			return nil, err
+		}
+		var comment string
+		if prop != nil {
+			comment = prop.Description
+		}
+		fields = append(fields, genField{key: key, typ: typ, comment: comment, required: required[key]})
+	}
+	return fields, nil
+}
+
+// resolve 解析引用并合并allOf
+func (g *testGenerator) resolve(schema *OpenAPISchema) (*OpenAPISchema, error) {
+	if schema.Ref != "" {
+		target, err := g.lookupRef(schema.Ref)
+		if err != nil {
+			return nil, err
+		}
+		return g.resolve(target)
+	}
+	if len(schema.AllOf) == 0 {
+		return schema, nil
+	}
+
+	merged := *schema
+	merged.AllOf = nil
+	merged.Properties = make(map[string]*OpenAPISchema)
+	for k, v := range schema.Properties {
+		merged.Properties[k] = v
+	}
+	for _, sub := range schema.AllOf {
+		sub, err := g.resolve(sub)
+		if err != nil {
+			return nil, err
+		}
+		if merged.Type == "" {
+			merged.Type = sub.Type
+		}
+		for k, v := range sub.Properties {
+			merged.Properties[k] = v
+		}
+		merged.Required = append(merged.Required, sub.Required...)
+	}
+	return &merged, nil
+}
+
+const componentSchemaPrefix = "#/components/schemas/"
+
+func (g *testGenerator) lookupRef(ref string) (*OpenAPISchema, error) {
+	if !strings.HasPrefix(ref, componentSchemaPrefix) {
+		return nil, fmt.Errorf("not support $ref %q", ref)
+	}
+	key := strings.TrimPrefix(ref, componentSchemaPrefix)
+	if g.doc.Components == nil || g.doc.Components.Schemas[key] == nil {
+		return nil, fmt.Errorf("$ref %q not found", ref)
+	}
+	return g.doc.Components.Schemas[key], nil
+}
+
+// defineRef 组件里的schema以它的名字定义类型
+func (g *testGenerator) defineRef(ref string) (string, error) {
+	target, err := g.lookupRef(ref)
+	if err != nil {
+		return "", err
+	}
+	name := goName(strings.TrimPrefix(ref, componentSchemaPrefix))
+	if g.defined[name] || g.defining[name] {
+		return name, nil
+	}
+
+	resolved, err := g.resolve(target)
+	if err != nil {
+		return "", err
+	}
+	if len(resolved.Properties) == 0 { // 非对象类型，定义为别名类型
+		g.defining[name] = true
+		typ, err := g.goType(resolved, name+"Value")
+		delete(g.defining, name)
+		if err != nil {
+			return "", err
+		}
+		g.defined[name] = true
+		g.types = append(g.types, typeComment(name, resolved.Description)+fmt.Sprintf("type %s %s\n", name, typ))
+		return name, nil
+	}
+
+	g.defining[name] = true
+	fields, err := g.schemaFields(name, resolved)
+	delete(g.defining, name)
+	if err != nil {
+		return "", err
+	}
+	g.defineStruct(name, resolved.Description, fields)
+	return name, nil
+}
+
+func (g *testGenerator) defineStruct(name, comment string, fields []genField) {
+	if g.defined[name] {
+		return
+	}
+	g.defined[name] = true
+
+	if comment == "" {
+		comment = g.comments[name]
+	}
+
+	var b strings.Builder
+	b.WriteString(typeComment(name, comment))
+	fmt.Fprintf(&b, "type %s struct {\n", name)
+	used := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		tag := f.key
+		if !f.required {
+			tag += ",omitempty"
+		}
+		// 不同的键可能得到相同的字段名，如：user_id和userId
+		fieldName := goName(f.key)
+		base := fieldName
+		for i := 2; used[fieldName]; i++ {
+			fieldName = fmt.Sprintf("%s%d", base, i)
+		}
+		used[fieldName] = true
+		fmt.Fprintf(&b, "%s %s `json:%q`", fieldName, f.typ, tag)
+		if f.comment != "" {
+			b.WriteString(" // " + oneLine(f.comment))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("}\n")
+	g.types = append(g.types, b.String())
+}
+
+func typeComment(name, comment string) string {
+	if comment == "" {
+		return ""
+	}
+	return fmt.Sprintf("// %s %s\n", name, oneLine(comment))
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// pickMedia 优先使用json，其次xml
+func pickMedia(content map[string]*OpenAPIMediaType) (string, *OpenAPIMediaType) {
+	keys := make([]string, 0, len(content))
+	for key := range content {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, want := range []string{"json", "xml"} {
+		for _, key := range keys {
+			if strings.Contains(key, want) {
+				return key, content[key]
+			}
+		}
+	}
+	if len(keys) > 0 {
+		return keys[0], content[keys[0]]
+	}
+	return "", nil
+}
+
+func formatOf(contentType string) string {
+	if strings.Contains(contentType, "xml") {
+		return "xml"
+	}
+	if strings.Contains(contentType, formContentType) {
+		return formParamFormat
+	}
+	return "json"
+}
+
+// ginPath 将OpenAPI的路径参数转为gin风格，如：/book/{id} -> /book/:id
+func ginPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			parts[i] = ":" + part[1:len(part)-1]
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func httpMethodConst(method string) string {
+	name := strings.ToUpper(method[:1]) + strings.ToLower(method[1:])
+	return "http.Method" + name
+}
+
+// goName 转为导出的Go名字，如：get_book-list -> GetBookList
+func goName(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if b.Len() == 0 && unicode.IsDigit(r) {
+			b.WriteString("X")
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 {
+		return "X"
+	}
+	return b.String()
+}
@@ -0,0 +1,164 @@
+package apitest
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenTestFromOpenAPI(t *testing.T) {
+	doc, err := ReadOpenAPI("testdata/book.openapi.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := GenTestFromOpenAPI(doc, "api", buf); err != nil {
+		t.Fatal(err)
+	}
+	code := buf.String()
+	for _, want := range []string{
+		"package api",
+		"// GetBookParam 获取图书信息的参数",
+		"Id         int64 `json:\"id\"`                    // 图书id",
+		"WithAuthor bool  `json:\"with_author,omitempty\"` // 是否返回作者信息",
+		"Birthday time.Time `json:\"birthday,omitempty\"` // 生日",
+		"Related []*Book `json:\"related,omitempty\"` // 相关图书",
+		"Code int    `json:\"code\"` // 业务码",
+		"Data Book   `json:\"data,omitempty\"`",
+		"Name   string   `json:\"name\"` // 书名",
+		"// TestGetBook 获取图书信息",
+		`apitest.NewAT("/book/:id", http.MethodGet, "获取图书信息", nil, nil).`,
+		`MarkAuthHeader("Authorization", "").`,
+		"SetParam(&GetBookParam{}).",
+		"var r BookResult",
+		// 没有operationId时根据方法和路径生成
+		"func TestPostBook(t *testing.T) {",
+		"UseXMLResultFormat().",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("code doesn't contain %q", want)
+		}
+	}
+	if t.Failed() {
+		t.Log(code)
+	}
+}
+
+func TestOpenAPIRoundTrip(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "openapi.json")
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewAT("/book/:id", http.MethodGet, "获取图书信息", nil, nil).
+		SetParam(&bookParam{Id: 1}).
+		FakeRun().
+		Result(&Result[bookResult]{}).
+		WriteOpenAPI(f).
+		Err(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	doc, err := ReadOpenAPI(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	if err := GenTestFromOpenAPI(doc, "api", buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"type GetBookIdParam struct {",
+		"Id int `json:\"id\"`",
+		"type GetBookIdResultData struct {",
+		`apitest.NewAT("/book/:id", http.MethodGet, "获取图书信息", nil, nil).`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("code doesn't contain %q\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestGenTestFromOpenAPIPathItem(t *testing.T) {
+	doc, err := ReadOpenAPI("testdata/pet.openapi.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := doc.Paths["/shop/{shopId}/pet/{petId}"]
+	if item == nil || item.Summary != "宠物" || len(item.Parameters) != 3 || len(item.Servers) != 1 {
+		t.Fatalf("bad path item: %+v", item)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := GenTestFromOpenAPI(doc, "api", buf); err != nil {
+		t.Fatal(err)
+	}
+	code := buf.String()
+	for _, want := range []string{
+		"// GetPetParam 宠物的参数",
+		"ShopId int64  `json:\"shopId\"`         // 店铺id",
+		"PetId  int64  `json:\"petId\"`          // 宠物id",
+		// 接口里的同名参数优先
+		"Lang   string `json:\"lang,omitempty\"` // 返回信息的语言",
+		"// DeletePetParam 删除宠物的参数",
+		`apitest.NewAT("/shop/:shopId/pet/:petId", http.MethodDelete, "删除宠物", nil, nil).`,
+		"SetParam(&DeletePetParam{}).",
+		"Lang   string `json:\"lang,omitempty\"` // 语言",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("code doesn't contain %q", want)
+		}
+	}
+	if t.Failed() {
+		t.Log(code)
+	}
+}
+
+func TestGenTestFromOpenAPICollision(t *testing.T) {
+	doc, err := ReadOpenAPI("testdata/collision.openapi.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := GenTestFromOpenAPI(doc, "api", buf); err != nil {
+		t.Fatal(err)
+	}
+	code := buf.String()
+	if n := strings.Count(code, "`json:\"id\"`"); n != 1 {
+		t.Fatalf("id should be defined once, have %d:\n%s", n, code)
+	}
+	for _, want := range []string{
+		"UserId2 ",
+		"OwnerInfo2 ",
+		"type UpdateBookParamOwnerInfo2 struct",
+	} {
+		if !strings.Contains(code, want) {
+			t.Fatalf("code doesn't contain %q:\n%s", want, code)
+		}
+	}
+
+	// 生成的代码需要能通过编译
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	dir, err := os.MkdirTemp("testdata", "gen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, "api_test.go"), buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(goBin, "vet", "./"+filepath.ToSlash(dir)).CombinedOutput()
+	if err != nil {
+		t.Fatalf("vet generated code failed: %v\n%s\n%s", err, out, code)
+	}
+}
@@ -0,0 +1,241 @@
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/donnol/apitest/testtype"
+	"github.com/samber/lo"
+)
+
+type apiError struct {
+	code, msg string
+}
+
+func (e apiError) Code() string { return e.code }
+func (e apiError) Msg() string  { return e.msg }
+
+func TestWriteOpenAPI(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := NewAT("/user/:id", http.MethodPost, "修改用户", nil, nil).
+		MarkAuthHeader("authorization", "Bearer [TOKEN]").
+		SetStatus(StatusImplemented).
+		SetParam(&testtype.User{Id: 1, Name: "jd"}).
+		FakeRun().
+		Result(&testtype.User{}).
+		Errors(apiError{"1001", "用户不存在"}).
+		WriteOpenAPI(buf).
+		Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc OpenAPI
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenAPI != "3.1.0" || doc.Info.Title != "修改用户" {
+		t.Fatalf("bad doc: %+v", doc)
+	}
+	op := doc.Paths["/user/{id}"].Post
+	if op == nil {
+		t.Fatalf("no operation: %s", buf.Bytes())
+	}
+	if op.OperationID != "postUserId" || op.Status != "已实现" {
+		t.Errorf("bad operation: %+v", op)
+	}
+	if len(op.Parameters) != 1 || op.Parameters[0].In != "path" || op.Parameters[0].Name != "id" {
+		t.Errorf("bad path parameters: %+v", op.Parameters)
+	}
+	if !reflect.DeepEqual(op.Security, []map[string][]string{{"Authorization": {}}}) {
+		t.Errorf("bad security: %+v", op.Security)
+	}
+	if s := doc.Components.SecuritySchemes["Authorization"]; s == nil || s.In != "header" || s.Type != "apiKey" {
+		t.Errorf("bad security scheme: %+v", s)
+	}
+	if len(op.Errors) != 1 || op.Description == "" {
+		t.Errorf("bad errors: %+v, %q", op.Errors, op.Description)
+	}
+
+	schema := op.RequestBody.Content["application/json"].Schema
+	if schema.Type != "object" {
+		t.Fatalf("bad schema: %+v", schema)
+	}
+	for name, want := range map[string]OpenAPISchema{
+		"id":   {Type: "string", Description: "id"},
+		"name": {Type: "string", Description: "名字"},
+		"age":  {Type: "integer", Format: "int32", Description: "年龄"},
+	} {
+		if got := schema.Properties[name]; got == nil || got.Type != want.Type || got.Format != want.Format || got.Description != want.Description {
+			t.Errorf("bad property %s: %+v", name, got)
+		}
+	}
+	// 内嵌结构体的字段提升到当前层级
+	if got := schema.Properties["phone"]; got == nil || got.Description != "手机" {
+		t.Errorf("bad embed property: %+v", got)
+	}
+	if got := schema.Properties["addr"]; got == nil || got.Properties["city"] == nil || got.Properties["city"].Description != "城市" {
+		t.Errorf("bad nested property: %+v", got)
+	}
+	if !lo.Contains(schema.Required, "name") {
+		t.Errorf("bad required: %v", schema.Required)
+	}
+
+	if _, ok := op.Responses["200"].Content["application/json"]; !ok {
+		t.Errorf("bad response: %+v", op.Responses)
+	}
+}
+
+func TestMakeOpenAPI(t *testing.T) {
+	collector := NewCollector(bookAPI{}, map[string]lo.Tuple2[reflect.Value, int]{
+		ApiKey(http.MethodGet, "/book"): lo.T2(reflect.ValueOf(getBook), 1),
+	})
+
+	dir := t.TempDir()
+	MakeOpenAPI(&docHelper{T: t, Collector: collector}, dir, "openapi.json", "图书", "")
+
+	data, err := os.ReadFile(filepath.Join(dir, "openapi.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc OpenAPI
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	op := doc.Paths["/api/book"].Get
+	if op == nil {
+		t.Fatalf("no operation: %s", data)
+	}
+	if op.Summary != "获取图书信息" || len(op.Parameters) != 1 || op.Parameters[0].In != "query" || op.Parameters[0].Name != "id" {
+		t.Errorf("bad operation: %s", data)
+	}
+}
+
+type docHelper struct {
+	*testing.T
+	*Collector
+}
+
+func (h *docHelper) GetParamResult(key string, param, result reflect.Type) (p, r any) {
+	return GenParamResult(key, param, result)
+}
+
+type treeNode struct {
+	Name     string     `json:"name"`
+	Children []treeNode `json:"children"`
+	Parent   *treeNode  `json:"parent,omitempty"`
+}
+
+func TestWriteOpenAPIRecursiveType(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := NewAT("/tree", http.MethodGet, "树", nil, nil).
+		FakeRun().
+		Result(&treeNode{}).
+		WriteOpenAPI(buf).
+		Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc OpenAPI
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	const ref = "#/components/schemas/treeNode"
+	if s := doc.Paths["/tree"].Get.Responses["200"].Content["application/json"].Schema; s.Ref != ref {
+		t.Fatalf("bad result schema: %+v", s)
+	}
+	if doc.Components == nil || doc.Components.Schemas["treeNode"] == nil {
+		t.Fatalf("no component schema: %s", buf.Bytes())
+	}
+	node := doc.Components.Schemas["treeNode"]
+	if node.Properties["name"] == nil || node.Properties["name"].Type != "string" {
+		t.Errorf("bad name property: %+v", node.Properties["name"])
+	}
+	if children := node.Properties["children"]; children == nil || children.Type != "array" || children.Items.Ref != ref {
+		t.Errorf("bad children property: %+v", children)
+	}
+	if parent := node.Properties["parent"]; parent == nil || parent.Ref != ref {
+		t.Errorf("bad parent property: %+v", parent)
+	}
+}
+
+type zzInner struct {
+	A string `json:"a"`
+}
+
+type zzOuter struct {
+	zzInner
+	B string `json:"b"`
+}
+
+type listNode struct {
+	Value int       `json:"value"`
+	Next  *listNode `json:"next,omitempty"`
+}
+
+type listHead struct {
+	listNode
+	Size int `json:"size"`
+}
+
+func TestWriteOpenAPIEmbed(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := NewAT("/embed", http.MethodPost, "内嵌", nil, nil).
+		SetParam(&zzOuter{}).
+		FakeRun().
+		Result(&listHead{}).
+		WriteOpenAPI(buf).
+		Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc OpenAPI
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	op := doc.Paths["/embed"].Post
+	// 与encoding/json一样，非导出的内嵌结构体的字段也会提升
+	param := op.RequestBody.Content["application/json"].Schema
+	if param.Properties["a"] == nil || param.Properties["b"] == nil {
+		t.Fatalf("bad param schema: %s", buf.Bytes())
+	}
+	// 内嵌的递归类型展开被引用的schema
+	result := op.Responses["200"].Content["application/json"].Schema
+	if result.Properties["value"] == nil || result.Properties["next"] == nil || result.Properties["size"] == nil {
+		t.Fatalf("bad result schema: %s", buf.Bytes())
+	}
+}
+
+// TreeNode 与testtype.TreeNode同名
+type TreeNode struct {
+	Label    string     `json:"label"`
+	Children []TreeNode `json:"children"`
+}
+
+func TestOpenAPISameNameType(t *testing.T) {
+	doc := NewOpenAPI("同名类型")
+	for _, at := range []*AT{
+		NewAT("/tree", http.MethodGet, "树", nil, nil).FakeRun().Result(&TreeNode{}),
+		NewAT("/tree2", http.MethodGet, "另一个包的树", nil, nil).FakeRun().Result(&testtype.TreeNode{}),
+	} {
+		if err := doc.Add(at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	schemas := doc.Components.Schemas
+	if s := schemas["TreeNode"]; s == nil || s.Properties["label"] == nil {
+		t.Fatalf("bad TreeNode: %+v", s)
+	}
+	if s := schemas["TesttypeTreeNode"]; s == nil || s.Properties["title"] == nil {
+		t.Fatalf("bad TesttypeTreeNode: %+v", s)
+	}
+	ref := doc.Paths["/tree2"].Get.Responses["200"].Content["application/json"].Schema.Ref
+	if ref != componentSchemaPrefix+"TesttypeTreeNode" {
+		t.Fatalf("bad ref: %s", ref)
+	}
+}
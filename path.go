@@ -0,0 +1,379 @@
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// EqualPath 校验响应体里path对应的值，如：EqualPath("data.list[0].name", "jd")
+func (at *AT) EqualPath(path string, want any) *AT {
+	got, err := at.PathValue(path)
+	if err != nil {
+		at.setErr(err)
+		return at
+	}
+
+	if !equalPathValue(got, want, at.resultFormat == "xml") {
+		at.setErr(fmt.Errorf("path %q not equal, have %s, want %s", path, fragment(got), fragment(want)))
+		return at
+	}
+
+	return at
+}
+
+// ExistsPath 校验响应体里存在path
+func (at *AT) ExistsPath(path string) *AT {
+	if _, err := at.PathValue(path); err != nil {
+		at.setErr(err)
+		return at
+	}
+	return at
+}
+
+// NotExistsPath 校验响应体里不存在path
+func (at *AT) NotExistsPath(path string) *AT {
+	got, err := at.PathValue(path)
+	if err == nil {
+		at.setErr(fmt.Errorf("path %q should not exist, have %s", path, fragment(got)))
+		return at
+	}
+	if _, ok := err.(*PathError); !ok {
+		at.setErr(err)
+	}
+	return at
+}
+
+// LenPath 校验响应体里path对应的数组、对象或字符串的长度，json字符串的长度为字符数而不是字节数；
+// xml无法区分只出现一次的元素和字符串，path对应单个元素时长度总是1，包括只有文本的元素
+func (at *AT) LenPath(path string, want int) *AT {
+	got, err := at.PathValue(path)
+	if err != nil {
+		at.setErr(err)
+		return at
+	}
+
+	var l int
+	switch v := got.(type) {
+	case []any:
+		l = len(v)
+	case map[string]any:
+		l = len(v)
+		if at.resultFormat == "xml" { // xml里只有一个元素时，也视为列表
+			l = 1
+		}
+	case string:
+		l = utf8.RuneCountInString(v)
+		if at.resultFormat == "xml" {
+			l = 1
+		}
+	default:
+		at.setErr(fmt.Errorf("path %q has no length, have %s", path, fragment(got)))
+		return at
+	}
+	if l != want {
+		at.setErr(fmt.Errorf("path %q bad length, have %d, want %d, value is %s", path, l, want, fragment(got)))
+		return at
+	}
+
+	return at
+}
+
+// PathValue 获取响应体里path对应的值；json数字为json.Number，xml的值都是字符串
+func (at *AT) PathValue(path string) (any, error) {
+	data, _, err := copyResponseBody(at.resp)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := decodeTree(at.resultFormat, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return lookupPath(root, path, at.resultFormat == "xml")
+}
+
+// PathError 路径不存在
+type PathError struct {
+	Path    string // 完整路径
+	Segment string // 出错的部分
+	Value   any    // 出错时所在的值
+}
+
+func (e *PathError) Error() string {
+	return fmt.Sprintf("path %q not found at %q, value is %s", e.Path, e.Segment, fragment(e.Value))
+}
+
+type pathToken struct {
+	key      string
+	index    int
+	isKey    bool
+	wildcard bool // [*]，匹配列表的所有元素，只用于忽略路径
+}
+
+// parsePath 解析路径，如：data.list[0].name，$.data.list[0]，[0].id
+func parsePath(path string) ([]pathToken, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+	path = strings.TrimPrefix(path, ".")
+
+	var tokens []pathToken
+	for path != "" {
+		switch path[0] {
+		case '.':
+			path = path[1:]
+		case '[':
+			end := strings.Index(path, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("bad path, missing ']' in %q", path)
+			}
+			inner := path[1:end]
+			if len(inner) >= 2 && (inner[0] == '"' || inner[0] == '\'') && inner[len(inner)-1] == inner[0] {
+				tokens = append(tokens, pathToken{key: inner[1 : len(inner)-1], isKey: true})
+			} else if inner == "*" {
+				tokens = append(tokens, pathToken{wildcard: true})
+			} else {
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("bad path index %q: %v", inner, err)
+				}
+				tokens = append(tokens, pathToken{index: index})
+			}
+			path = path[end+1:]
+		default:
+			end := strings.IndexAny(path, ".[")
+			if end == -1 {
+				end = len(path)
+			}
+			tokens = append(tokens, pathToken{key: path[:end], isKey: true})
+			path = path[end:]
+		}
+	}
+
+	return tokens, nil
+}
+
+func lookupPath(root any, path string, isXML bool) (any, error) {
+	tokens, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cur := root
+	for i, token := range tokens {
+		segment := pathString(tokens[:i+1])
+		if token.isKey {
+			m, ok := cur.(map[string]any)
+			if !ok {
+				return nil, &PathError{Path: path, Segment: segment, Value: cur}
+			}
+			v, ok := m[token.key]
+			if !ok {
+				return nil, &PathError{Path: path, Segment: segment, Value: cur}
+			}
+			cur = v
+			continue
+		}
+
+		if token.wildcard {
+			return nil, fmt.Errorf("bad path %q, wildcard is not supported here", path)
+		}
+		list, ok := cur.([]any)
+		if !ok && isXML { // xml里只有一个元素时，也视为列表
+			list, ok = []any{cur}, true
+		}
+		if !ok {
+			return nil, &PathError{Path: path, Segment: segment, Value: cur}
+		}
+		index := token.index
+		if index < 0 { // 支持倒数
+			index += len(list)
+		}
+		if index < 0 || index >= len(list) {
+			return nil, &PathError{Path: path, Segment: segment, Value: cur}
+		}
+		cur = list[index]
+	}
+
+	return cur, nil
+}
+
+func pathString(tokens []pathToken) string {
+	var b strings.Builder
+	for i, token := range tokens {
+		if token.isKey {
+			if i > 0 {
+				b.WriteString(".")
+			}
+			b.WriteString(token.key)
+		} else if token.wildcard {
+			b.WriteString("[*]")
+		} else {
+			fmt.Fprintf(&b, "[%d]", token.index)
+		}
+	}
+	return b.String()
+}
+
+// decodeTree 将响应体解析为由map[string]any、[]any和基础类型组成的树
+func decodeTree(format string, data []byte) (any, error) {
+	switch format {
+	case "xml":
+		return decodeXMLTree(data)
+	default:
+		var v any
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&v); err != nil {
+			return nil, fmt.Errorf("json decode failed: %+v, data: %s", err, data)
+		}
+		return v, nil
+	}
+}
+
+// decodeXMLTree 将xml解析为树：根元素本身被省略，子元素作为键，重复的子元素合并为列表，属性的键以'@'开头，同时有子元素和文本时文本的键为'#text'
+func decodeXMLTree(data []byte) (any, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("xml decode failed: no root element, data: %s", data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("xml decode failed: %+v, data: %s", err, data)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			v, err := decodeXMLElement(decoder, start)
+			if err != nil {
+				return nil, fmt.Errorf("xml decode failed: %+v, data: %s", err, data)
+			}
+			return v, nil
+		}
+	}
+}
+
+func decodeXMLElement(decoder *xml.Decoder, start xml.StartElement) (any, error) {
+	m := make(map[string]any)
+	for _, attr := range start.Attr {
+		m["@"+attr.Name.Local] = attr.Value
+	}
+
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			child, err := decodeXMLElement(decoder, t)
+			if err != nil {
+				return nil, err
+			}
+			name := t.Name.Local
+			if old, ok := m[name]; ok {
+				if list, ok := old.([]any); ok {
+					m[name] = append(list, child)
+				} else {
+					m[name] = []any{old, child}
+				}
+			} else {
+				m[name] = child
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			s := strings.TrimSpace(text.String())
+			if len(m) == 0 {
+				return s, nil
+			}
+			if s != "" {
+				m["#text"] = s
+			}
+			return m, nil
+		}
+	}
+}
+
+// equalPathValue 比较路径的值，want先转为与json解析结果一致的形式再比较
+func equalPathValue(got, want any, isXML bool) bool {
+	if isXML {
+		if s, ok := got.(string); ok {
+			return s == fmt.Sprint(want)
+		}
+	}
+
+	data, err := json.Marshal(want)
+	if err != nil {
+		return false
+	}
+	normalized, err := decodeTree("json", data)
+	if err != nil {
+		return false
+	}
+
+	return equalTree(got, normalized)
+}
+
+func equalTree(a, b any) bool {
+	switch av := a.(type) {
+	case json.Number:
+		bv, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		if av == bv {
+			return true
+		}
+		af, aerr := av.Float64()
+		bf, berr := bv.Float64()
+		return aerr == nil && berr == nil && af == bf
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equalTree(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			w, ok := bv[k]
+			if !ok || !equalTree(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// fragment 将值转为便于阅读的片段，过长时截断
+func fragment(v any) string {
+	const (
+		max = 256
+	)
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	if len(data) > max {
+		return string(data[:max]) + "..."
+	}
+	return string(data)
+}
@@ -0,0 +1,74 @@
+package apitest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPathAssert(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":0,"msg":"","data":{"total":2,"price":1.5,"list":[{"name":"jd","tags":["a","b"]},{"name":"jc","tags":[]}],"a.b":true,"empty":null,"nick":"张三"}}`))
+	})
+	mux.HandleFunc("/xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<?xml version="1.0"?><result><code>0</code><data total="2"><list><name>jd</name></list><list><name>jc</name></list><single><name>one</name></single><nick>张三</nick></data></result>`))
+	})
+
+	t.Run("json", func(t *testing.T) {
+		at := NewAT("/json", http.MethodGet, "path", nil, nil).SetHandler(mux).Run()
+		if err := at.
+			EqualPath("code", 0).
+			EqualPath("$.data.total", 2).
+			EqualPath("data.price", 1.5).
+			EqualPath("data.list[0].name", "jd").
+			EqualPath("data.list[-1].name", "jc").
+			EqualPath("data.list[0].tags", []string{"a", "b"}).
+			EqualPath(`data["a.b"]`, true).
+			EqualPath("data.empty", nil).
+			ExistsPath("data.list[1].tags").
+			NotExistsPath("data.list[2]").
+			NotExistsPath("data.none").
+			LenPath("data.list", 2).
+			LenPath("data.list[1].tags", 0).
+			LenPath("data.nick", 2).
+			Err(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("xml", func(t *testing.T) {
+		at := NewAT("/xml", http.MethodGet, "path", nil, nil).SetHandler(mux).UseXMLResultFormat().Run()
+		if err := at.
+			EqualPath("code", 0).
+			EqualPath("data.@total", "2").
+			EqualPath("data.list[1].name", "jc").
+			EqualPath("data.single[0].name", "one").
+			LenPath("data.list", 2).
+			LenPath("data.single", 1).
+			LenPath("data.nick", 1). // 只有文本的单个元素
+			NotExistsPath("data.list[2]").
+			Err(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	for _, tc := range []struct {
+		name string
+		f    func(at *AT) *AT
+		want string
+	}{
+		{"not equal", func(at *AT) *AT { return at.EqualPath("data.list[0].name", "jc") }, `path "data.list[0].name" not equal, have "jd", want "jc"`},
+		{"not found", func(at *AT) *AT { return at.ExistsPath("data.list[0].age") }, `path "data.list[0].age" not found at "data.list[0].age", value is {"name":"jd","tags":["a","b"]}`},
+		{"bad length", func(at *AT) *AT { return at.LenPath("data.list", 3) }, `path "data.list" bad length, have 2, want 3`},
+		{"exists", func(at *AT) *AT { return at.NotExistsPath("code") }, `path "code" should not exist, have 0`},
+		{"bad path", func(at *AT) *AT { return at.ExistsPath("data.list[a]") }, `bad path index "a"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.f(NewAT("/json", http.MethodGet, "path", nil, nil).SetHandler(mux).Run()).Err()
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("bad error: %v, want %s", err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,441 @@
+package apitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	postmanSchema     = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+	postmanBaseURLVar = "baseUrl"
+)
+
+type (
+	// PostmanCollection Postman v2.1集合，Insomnia也可以直接导入
+	PostmanCollection struct {
+		Info     PostmanInfo       `json:"info"`
+		Item     []*PostmanItem    `json:"item"`
+		Variable []PostmanVariable `json:"variable,omitempty"`
+	}
+
+	PostmanInfo struct {
+		Name   string `json:"name"`
+		Schema string `json:"schema"`
+	}
+
+	// PostmanItem 有Request时为请求，否则为目录
+	PostmanItem struct {
+		Name     string             `json:"name"`
+		Item     []*PostmanItem     `json:"item,omitempty"`
+		Request  *PostmanRequest    `json:"request,omitempty"`
+		Response []*PostmanResponse `json:"response,omitempty"`
+	}
+
+	PostmanRequest struct {
+		Method      string          `json:"method"`
+		Header      []PostmanHeader `json:"header"`
+		Body        *PostmanBody    `json:"body,omitempty"`
+		URL         PostmanURL      `json:"url"`
+		Description string          `json:"description,omitempty"`
+	}
+
+	PostmanHeader struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	}
+
+	PostmanURL struct {
+		Raw      string            `json:"raw"`
+		Host     []string          `json:"host"`
+		Path     []string          `json:"path"`
+		Query    []PostmanQuery    `json:"query,omitempty"`
+		Variable []PostmanVariable `json:"variable,omitempty"` // 路径参数，如：/book/:id
+	}
+
+	PostmanQuery struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	}
+
+	PostmanVariable struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	}
+
+	PostmanBody struct {
+		Mode       string              `json:"mode"` // raw, formdata, urlencoded
+		Raw        string              `json:"raw,omitempty"`
+		FormData   []PostmanFormData   `json:"formdata,omitempty"`
+		URLEncoded []PostmanQuery      `json:"urlencoded,omitempty"`
+		Options    *PostmanBodyOptions `json:"options,omitempty"`
+	}
+
+	PostmanFormData struct {
+		Key   string `json:"key"`
+		Type  string `json:"type"` // text, file
+		Value string `json:"value,omitempty"`
+		Src   string `json:"src,omitempty"`
+	}
+
+	PostmanBodyOptions struct {
+		Raw struct {
+			Language string `json:"language"` // json, xml
+		} `json:"raw"`
+	}
+
+	PostmanResponse struct {
+		Name                   string          `json:"name"`
+		OriginalRequest        *PostmanRequest `json:"originalRequest,omitempty"`
+		Status                 string          `json:"status"`
+		Code                   int             `json:"code"`
+		PostmanPreviewLanguage string          `json:"_postman_previewlanguage,omitempty"`
+		Header                 []PostmanHeader `json:"header"`
+		Body                   string          `json:"body"`
+	}
+)
+
+// NewPostmanCollection 新建Postman集合
+func NewPostmanCollection(name string) *PostmanCollection {
+	return &PostmanCollection{
+		Info: PostmanInfo{
+			Name:   name,
+			Schema: postmanSchema,
+		},
+	}
+}
+
+// Add 添加请求到folder目录下，folder为空时添加到顶层
+func (c *PostmanCollection) Add(folder string, at *AT) error {
+	item, err := at.postmanItem()
+	if err != nil {
+		return err
+	}
+
+	// 服务器地址和认证信息作为集合变量，方便统一修改
+	u := at.buildURL()
+	c.setVariable(postmanBaseURLVar, u.Scheme+"://"+u.Host, false)
+	if at.authHeaderKey != "" {
+		c.setVariable(postmanAuthVar(at.authHeaderKey), at.authHeaderValue, false)
+	}
+
+	if folder == "" {
+		c.Item = append(c.Item, item)
+		return nil
+	}
+	for _, f := range c.Item {
+		if f.Request == nil && f.Name == folder {
+			f.Item = append(f.Item, item)
+			return nil
+		}
+	}
+	c.Item = append(c.Item, &PostmanItem{Name: folder, Item: []*PostmanItem{item}})
+	return nil
+}
+
+// setVariable 已存在时，override为true才覆盖
+func (c *PostmanCollection) setVariable(key, value string, override bool) {
+	for i, v := range c.Variable {
+		if v.Key == key {
+			if override {
+				c.Variable[i].Value = value
+			}
+			return
+		}
+	}
+	c.Variable = append(c.Variable, PostmanVariable{Key: key, Value: value})
+}
+
+// WriteTo 以json格式写入
+func (c *PostmanCollection) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.MarshalIndent(c, "", "    ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(append(data, '\n'))
+	return int64(n), err
+}
+
+// WritePostman 写入只包含当前接口的Postman集合
+func (at *AT) WritePostman(w io.Writer) *AT {
+	if w == nil {
+		at.setErr(fmt.Errorf("nil writer"))
+		return at
+	}
+
+	c := NewPostmanCollection(at.comment)
+	if err := c.Add("", at); err != nil {
+		at.setErr(err)
+		return at
+	}
+	if _, err := c.WriteTo(w); err != nil {
+		at.setErr(err)
+		return at
+	}
+
+	return at
+}
+
+// PostmanCollection 导出收集到的接口，接口按与FindTestAPIsByPrefix相同的路径前缀放到目录里，匹配多个前缀时使用最长的，没有匹配的放在顶层
+func (c *Collector) PostmanCollection(name string, prefixes ...string) (*PostmanCollection, error) {
+	collection := NewPostmanCollection(name)
+	for _, item := range c.FindTestAPIsByPrefix("") {
+		if err := collection.Add(postmanFolder(item.Path(), prefixes), item.AT); err != nil {
+			return nil, err
+		}
+	}
+	return collection, nil
+}
+
+// MakePostman 与MakeDoc一样收集并虚假执行接口，生成Postman集合文件
+func MakePostman(t DocHelper, dir, file, name string, prefixes ...string) {
+	collection := NewPostmanCollection(name)
+	for _, item := range t.FindTestAPIsByPrefix("") {
+		at := item
+		p, r := at.GetParamResult(t.GetParamResult)
+		if err := at.SetParam(p).
+			FakeRun().
+			Result(r).
+			Err(); err != nil {
+			t.Fatal(err)
+		}
+		if err := collection.Add(postmanFolder(at.Path(), prefixes), at.AT); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, file), os.O_CREATE|os.O_TRUNC|os.O_RDWR, os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := collection.WriteTo(f); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func postmanFolder(path string, prefixes []string) string {
+	var folder string
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) && len(prefix) > len(folder) {
+			folder = prefix
+		}
+	}
+	return folder
+}
+
+// postmanAuthVar 认证header对应的变量名，如：Authorization -> authorization
+func postmanAuthVar(key string) string {
+	return strings.ToLower(http.CanonicalHeaderKey(key))
+}
+
+// postmanItem 使用已执行的请求和响应；还没执行时，根据参数构造请求
+func (at *AT) postmanItem() (*PostmanItem, error) {
+	req, reqBody := at.req, at.reqBody
+	if req == nil {
+		ex := at.newExchange()
+		if err := at.prepare(ex); err != nil {
+			return nil, err
+		}
+		req, reqBody = ex.req, ex.reqBody
+	}
+
+	request, err := at.postmanRequest(req, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	item := &PostmanItem{
+		Name:    at.commentWithStatus(),
+		Request: request,
+	}
+
+	// 响应示例
+	if at.resp != nil {
+		data, _, err := copyResponseBody(at.resp)
+		if err != nil {
+			return nil, err
+		}
+		item.Response = append(item.Response, &PostmanResponse{
+			Name:                   at.comment,
+			OriginalRequest:        request,
+			Status:                 http.StatusText(at.resp.StatusCode),
+			Code:                   at.resp.StatusCode,
+			PostmanPreviewLanguage: previewLanguage(at.resp.Header.Get("Content-Type")),
+			Header:                 postmanHeaders(at.resp.Header, ""),
+			Body:                   string(data),
+		})
+	}
+
+	return item, nil
+}
+
+func (at *AT) postmanRequest(req *http.Request, reqBody []byte) (*PostmanRequest, error) {
+	request := &PostmanRequest{
+		Method:      at.method,
+		Header:      postmanHeaders(req.Header, at.authHeaderKey),
+		Description: at.comment,
+	}
+	if at.authHeaderKey != "" {
+		request.Header = append(request.Header, PostmanHeader{
+			Key:   http.CanonicalHeaderKey(at.authHeaderKey),
+			Value: "{{" + postmanAuthVar(at.authHeaderKey) + "}}",
+		})
+	}
+
+	// 链接，路径使用at.path以保留路径参数
+	path := at.path
+	if i := strings.Index(path, "?"); i != -1 {
+		path = path[:i]
+	}
+	raw := "{{" + postmanBaseURLVar + "}}" + path
+	request.URL = PostmanURL{
+		Host: []string{"{{" + postmanBaseURLVar + "}}"},
+		Path: strings.Split(strings.TrimPrefix(path, "/"), "/"),
+	}
+	for _, part := range request.URL.Path {
+		if strings.HasPrefix(part, ":") {
+			request.URL.Variable = append(request.URL.Variable, PostmanVariable{Key: part[1:]})
+		}
+	}
+	if req.URL.RawQuery != "" {
+		raw += "?" + req.URL.RawQuery
+		query := req.URL.Query()
+		keys := make([]string, 0, len(query))
+		for key := range query {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			for _, value := range query[key] {
+				request.URL.Query = append(request.URL.Query, PostmanQuery{Key: key, Value: value})
+			}
+		}
+	}
+	request.URL.Raw = raw
+
+	// 请求体
+	switch {
+	case at.file != "":
+		request.Body = &PostmanBody{
+			Mode:     "formdata",
+			FormData: []PostmanFormData{{Key: "file", Type: "file", Src: at.file}},
+		}
+		// Content-Type由Postman根据formdata生成
+		request.Header = removePostmanHeader(request.Header, "Content-Type")
+	case at.multipart != nil:
+		fields, err := paramFields(at.param)
+		if err != nil {
+			return nil, err
+		}
+		body := &PostmanBody{Mode: "formdata"}
+		for _, field := range fields {
+			body.FormData = append(body.FormData, PostmanFormData{Key: field.name, Type: "text", Value: field.value})
+		}
+		for _, part := range at.multipart.parts {
+			if !part.isFile {
+				body.FormData = append(body.FormData, PostmanFormData{Key: part.name, Type: "text", Value: part.value})
+				continue
+			}
+			src := part.path
+			if src == "" {
+				src = part.fileName
+			}
+			body.FormData = append(body.FormData, PostmanFormData{Key: part.name, Type: "file", Src: src})
+		}
+		request.Body = body
+		request.Header = removePostmanHeader(request.Header, "Content-Type")
+	case at.paramFormat == formParamFormat && len(reqBody) > 0:
+		values, err := url.ParseQuery(string(reqBody))
+		if err != nil {
+			return nil, fmt.Errorf("parse form body failed: %w", err)
+		}
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		body := &PostmanBody{Mode: "urlencoded"}
+		for _, key := range keys {
+			for _, value := range values[key] {
+				body.URLEncoded = append(body.URLEncoded, PostmanQuery{Key: key, Value: value})
+			}
+		}
+		request.Body = body
+	case at.paramFormat == protobufFormat && at.param != nil:
+		// 二进制内容无法在Postman里编辑，使用protojson
+		data, err := protoJSON(at.param)
+		if err != nil {
+			return nil, err
+		}
+		request.Body = &PostmanBody{
+			Mode:    "raw",
+			Raw:     string(data),
+			Options: &PostmanBodyOptions{},
+		}
+		request.Body.Options.Raw.Language = "json"
+	case len(reqBody) > 0:
+		request.Body = &PostmanBody{
+			Mode:    "raw",
+			Raw:     string(reqBody),
+			Options: &PostmanBodyOptions{},
+		}
+		switch {
+		case at.paramFormat == "xml":
+			request.Body.Options.Raw.Language = "xml"
+		case isBuiltinParamFormat(at.paramFormat):
+			request.Body.Options.Raw.Language = "json"
+		default:
+			request.Body.Options.Raw.Language = "text"
+		}
+	}
+
+	return request, nil
+}
+
+// postmanHeaders 按键排序，跳过skip
+func postmanHeaders(h http.Header, skip string) []PostmanHeader {
+	keys := make([]string, 0, len(h))
+	for key := range h {
+		if skip != "" && http.CanonicalHeaderKey(key) == http.CanonicalHeaderKey(skip) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	headers := make([]PostmanHeader, 0, len(keys))
+	for _, key := range keys {
+		for _, value := range h[key] {
+			headers = append(headers, PostmanHeader{Key: key, Value: value})
+		}
+	}
+	return headers
+}
+
+func removePostmanHeader(headers []PostmanHeader, key string) []PostmanHeader {
+	r := headers[:0]
+	for _, h := range headers {
+		if http.CanonicalHeaderKey(h.Key) != key {
+			r = append(r, h)
+		}
+	}
+	return r
+}
+
+func previewLanguage(contentType string) string {
+	switch {
+	case strings.Contains(contentType, "json"):
+		return "json"
+	case strings.Contains(contentType, "xml"):
+		return "xml"
+	case strings.Contains(contentType, "html"):
+		return "html"
+	}
+	return "text"
+}
@@ -0,0 +1,115 @@
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/samber/lo"
+)
+
+func TestWritePostman(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write([]byte(`{"id":1,"name":"jd"}`))
+	})
+
+	buf := new(bytes.Buffer)
+	if err := NewAT("/user/:id", http.MethodPut, "修改用户", nil, nil).
+		SetHost("example.com").
+		SetHandler(mux).
+		MarkAuthHeader("authorization", "Bearer [TOKEN]").
+		SetParam(map[string]any{"name": "jd"}).
+		Run().
+		WritePostman(buf).
+		Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	var c PostmanCollection
+	if err := json.Unmarshal(buf.Bytes(), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Info.Schema != postmanSchema || len(c.Item) != 1 {
+		t.Fatalf("bad collection: %s", buf.Bytes())
+	}
+	if !reflect.DeepEqual(c.Variable, []PostmanVariable{
+		{Key: "baseUrl", Value: "http://example.com:80"},
+		{Key: "authorization", Value: "Bearer [TOKEN]"},
+	}) {
+		t.Errorf("bad variable: %+v", c.Variable)
+	}
+
+	req := c.Item[0].Request
+	if req.Method != http.MethodPut || req.URL.Raw != "{{baseUrl}}/user/:id" || !reflect.DeepEqual(req.URL.Path, []string{"user", ":id"}) {
+		t.Errorf("bad url: %+v", req.URL)
+	}
+	if !reflect.DeepEqual(req.URL.Variable, []PostmanVariable{{Key: "id"}}) {
+		t.Errorf("bad path variable: %+v", req.URL.Variable)
+	}
+	if !lo.Contains(req.Header, PostmanHeader{Key: "Authorization", Value: "{{authorization}}"}) {
+		t.Errorf("bad header: %+v", req.Header)
+	}
+	if req.Body == nil || req.Body.Mode != "raw" || req.Body.Raw != `{"name":"jd"}` || req.Body.Options.Raw.Language != "json" {
+		t.Errorf("bad body: %+v", req.Body)
+	}
+
+	if len(c.Item[0].Response) != 1 {
+		t.Fatalf("bad response: %+v", c.Item[0].Response)
+	}
+	resp := c.Item[0].Response[0]
+	if resp.Code != http.StatusOK || resp.Body != `{"id":1,"name":"jd"}` || resp.PostmanPreviewLanguage != "json" {
+		t.Errorf("bad response: %+v", resp)
+	}
+}
+
+func TestCollectorPostman(t *testing.T) {
+	collector := NewCollector(bookAPI{}, map[string]lo.Tuple2[reflect.Value, int]{
+		ApiKey(http.MethodGet, "/book"): lo.T2(reflect.ValueOf(getBook), 1),
+	})
+	apis := collector.FindTestAPIsByPrefix("/api/book")
+	if err := apis[0].SetParam(&bookParam{Id: 1}).Run().Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := collector.PostmanCollection("图书", "/api", "/api/book")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Item) != 1 || c.Item[0].Name != "/api/book" || len(c.Item[0].Item) != 1 {
+		t.Fatalf("bad folder: %+v", c.Item)
+	}
+	item := c.Item[0].Item[0]
+	if item.Name != "获取图书信息" || !reflect.DeepEqual(item.Request.URL.Query, []PostmanQuery{{Key: "id", Value: "1"}}) {
+		t.Errorf("bad item: %+v", item.Request)
+	}
+	if item.Request.URL.Raw != "{{baseUrl}}/api/book?id=1" {
+		t.Errorf("bad raw url: %s", item.Request.URL.Raw)
+	}
+	if len(item.Response) != 1 || item.Response[0].Body == "" {
+		t.Errorf("bad response: %+v", item.Response)
+	}
+}
+
+func TestPostmanAddError(t *testing.T) {
+	at := NewAT("/user", http.MethodPost, "新建用户", nil, nil).UseFormParamFormat()
+	// 已执行的请求体不是合法的表单
+	at.req = httptest.NewRequest(http.MethodPost, "/user", nil)
+	at.reqBody = []byte("name=%zz")
+
+	c := NewPostmanCollection("用户")
+	if err := c.Add("", at); err == nil || !strings.Contains(err.Error(), "parse form body failed") {
+		t.Fatalf("bad err: %v", err)
+	}
+	if len(c.Item) != 0 {
+		t.Fatalf("bad item: %+v", c.Item)
+	}
+	if err := at.Err(); err != nil {
+		t.Fatalf("error should be returned, not set on at: %v", err)
+	}
+}
@@ -0,0 +1,565 @@
+package apitest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/donnol/do"
+)
+
+const (
+	defaultSlowThreshold = 1 * time.Second
+)
+
+// PressureRun 压力运行，n: 运行次数，c: 并发数
+func (at *AT) PressureRun(n, c int) *AT {
+	return at.PressureRunBatch([]PressureParam{{N: n, C: c}})
+}
+
+// PressureRunFor 在时长d内以并发数c持续运行
+func (at *AT) PressureRunFor(d time.Duration, c int) *AT {
+	return at.PressureRunBatch([]PressureParam{{Duration: d, C: c}})
+}
+
+// PressureRunRate 在时长d内以每秒rate个请求的固定速率发起请求，不等待之前的请求完成
+func (at *AT) PressureRunRate(rate int, d time.Duration) *AT {
+	return at.PressureRunBatch([]PressureParam{{Rate: rate, Duration: d}})
+}
+
+// PressureRunWith 压力运行，每次运行使用paramFunc生成的参数，i为第几次运行，从0开始
+func (at *AT) PressureRunWith(n, c int, paramFunc func(i int) any) *AT {
+	return at.SetPressureParam(paramFunc).PressureRun(n, c)
+}
+
+// SetPressureParam 设置压力运行时每次运行的参数，i为第几次运行，从0开始
+func (at *AT) SetPressureParam(paramFunc func(i int) any) *AT {
+	at.pressureParam = paramFunc
+	return at
+}
+
+// SetPressureHeader 设置压力运行时每次运行额外的header，同名的会覆盖SetHeader设置的值，如从用户池里轮流取出token
+func (at *AT) SetPressureHeader(headerFunc func(i int) http.Header) *AT {
+	at.pressureHeader = headerFunc
+	return at
+}
+
+// paramFuncError 参数生成失败，在压力运行时作为该次请求的错误
+type paramFuncError struct {
+	err error
+}
+
+// MonkeyParamFunc 返回参数生成方法，与MonkeyRun一样根据参数结构体随机生成值，可用于SetPressureParam；
+// 生成失败时该次请求记为失败，不会panic
+func (at *AT) MonkeyParamFunc() func(i int) any {
+	typ := reflect.TypeOf(at.param)
+	return func(i int) any {
+		if typ == nil {
+			return nil
+		}
+		var v reflect.Value
+		if typ.Kind() == reflect.Ptr {
+			v = reflect.New(typ.Elem())
+		} else {
+			v = reflect.New(typ)
+		}
+		if err := gofakeit.Struct(v.Interface()); err != nil {
+			return paramFuncError{err: fmt.Errorf("generate param value failed: %w", err)}
+		}
+		if typ.Kind() == reflect.Ptr {
+			return v.Interface()
+		}
+		return v.Elem().Interface()
+	}
+}
+
+// PressureParam 压力测试参数
+//
+// 未设置Rate时为闭合模型：C个并发各自循环发起请求，直到运行了N次或者运行时长达到Duration，两者都设置时先达到者为准；
+// 设置了Rate时为开放模型：按每秒Rate个请求的速率发起请求，直到发起了N次或者运行时长达到Duration，此时C表示最大的未完成请求数，超出的请求会被丢弃并计入Dropped，为0时不限制。
+type PressureParam struct {
+	Name     string        // 阶段名
+	N        int           // 运行次数
+	C        int           // 并发数
+	Duration time.Duration // 运行时长
+	Rate     int           // 每秒请求数
+}
+
+// RampStages 生成逐步加压的阶段：速率从from开始，每个阶段增加step，直到不超过to，每个阶段持续each
+func RampStages(from, to, step int, each time.Duration) []PressureParam {
+	if step <= 0 {
+		step = 1
+	}
+	var stages []PressureParam
+	for rate := from; rate <= to; rate += step {
+		stages = append(stages, PressureParam{
+			Name:     fmt.Sprintf("ramp %d/s", rate),
+			Duration: each,
+			Rate:     rate,
+		})
+	}
+	return stages
+}
+
+// PressureRunBatch 批量压力运行，每个阶段生成一份报告
+func (at *AT) PressureRunBatch(param []PressureParam) *AT {
+	reports := make([]*PressureReport, 0, len(param))
+	for _, single := range param {
+		report, err := at.pressure(single)
+		if err != nil {
+			at.setErr(err)
+			break
+		}
+		reports = append(reports, report)
+
+		fmt.Print(report.String())
+	}
+	at.pressureReports = reports
+
+	return at
+}
+
+// PressureReport 获取最近一次压力运行的报告，批量运行时为最后一个阶段的报告
+func (at *AT) PressureReport() *PressureReport {
+	if len(at.pressureReports) == 0 {
+		return nil
+	}
+	return at.pressureReports[len(at.pressureReports)-1]
+}
+
+// PressureReports 获取最近一次压力运行的全部报告
+func (at *AT) PressureReports() []*PressureReport {
+	return at.pressureReports
+}
+
+func (at *AT) pressure(param PressureParam) (*PressureReport, error) {
+	if param.N <= 0 && param.Duration <= 0 {
+		return nil, fmt.Errorf("pressure param needs N or Duration: %+v", param)
+	}
+	if param.Rate <= 0 && param.C <= 0 {
+		return nil, fmt.Errorf("pressure param needs C or Rate: %+v", param)
+	}
+
+	recorder := newPressureRecorder(at.getSlowThreshold())
+
+	// 记录开始时间
+	before := time.Now()
+
+	var deadline time.Time
+	if param.Duration > 0 {
+		deadline = before.Add(param.Duration)
+	}
+	if param.Rate > 0 {
+		at.openPressure(recorder, param, before, deadline)
+	} else {
+		at.closedPressure(recorder, param, deadline)
+	}
+
+	// 记录结束时间，并计算耗时
+	report := recorder.report(param, time.Since(before))
+
+	// 以第一次运行的请求和响应作为样本，以序号最小的错误作为错误，保证结果是确定的
+	if sample := recorder.sample; sample != nil {
+		at.req = sample.req
+		at.reqBody = sample.reqBody
+		at.resp = sample.resp
+	}
+	if failure := recorder.failure; failure != nil {
+		at.setErr(failure.err)
+	}
+
+	return report, nil
+}
+
+// pressureOnce 以独立的上下文运行一次，结果交由recorder汇总
+func (at *AT) pressureOnce(recorder *pressureRecorder, i int) {
+	ex := at.newExchange()
+	ex.i = i
+	if at.pressureParam != nil {
+		ex.param = at.pressureParam(i)
+	}
+	if at.pressureHeader != nil {
+		header := at.header.Clone()
+		if header == nil {
+			header = make(http.Header)
+		}
+		for k, v := range at.pressureHeader(i) {
+			header[http.CanonicalHeaderKey(k)] = v
+		}
+		ex.header = header
+	}
+	if pe, ok := ex.param.(paramFuncError); ok {
+		ex.err = pe.err
+	} else if err := at.prepare(ex); err != nil {
+		ex.err = err
+	} else {
+		at.send(ex)
+	}
+
+	recorder.add(ex)
+}
+
+// closedPressure 闭合模型，C个并发各自循环运行
+func (at *AT) closedPressure(recorder *pressureRecorder, param PressureParam, deadline time.Time) {
+	var (
+		wg    sync.WaitGroup
+		count int64
+	)
+	for j := 0; j < param.C; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				if !deadline.IsZero() && !time.Now().Before(deadline) {
+					return
+				}
+				i := atomic.AddInt64(&count, 1) - 1
+				if param.N > 0 && i >= int64(param.N) {
+					return
+				}
+
+				at.pressureOnce(recorder, int(i))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// openPressure 开放模型，按固定速率发起请求
+func (at *AT) openPressure(recorder *pressureRecorder, param PressureParam, start, deadline time.Time) {
+	var (
+		wg       sync.WaitGroup
+		inflight int64
+	)
+
+	interval := time.Second / time.Duration(param.Rate)
+	for i := 0; param.N <= 0 || i < param.N; i++ {
+		// 按计划时间发起，避免ticker的累积误差
+		next := start.Add(time.Duration(i) * interval)
+		if !deadline.IsZero() && !next.Before(deadline) {
+			break
+		}
+		time.Sleep(time.Until(next))
+
+		if param.C > 0 && atomic.LoadInt64(&inflight) >= int64(param.C) {
+			recorder.drop()
+			continue
+		}
+
+		wg.Add(1)
+		atomic.AddInt64(&inflight, 1)
+		go func(i int) {
+			defer func() {
+				atomic.AddInt64(&inflight, -1)
+				wg.Done()
+			}()
+
+			at.pressureOnce(recorder, i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func (at *AT) getSlowThreshold() time.Duration {
+	if at.slowThreshold > 0 {
+		return at.slowThreshold
+	}
+	return defaultSlowThreshold
+}
+
+// PressureReport 压力测试报告
+type PressureReport struct {
+	Name      string        // 阶段名
+	N         int           // 运行次数
+	C         int           // 并发数
+	Duration  time.Duration // 计划运行时长
+	Rate      int           // 计划每秒请求数
+	Completed int           // 完成数，包括出错的请求
+	Dropped   int           // 开放模型下因未完成请求过多而丢弃的请求数
+	Failed    int           // 出错数，即没有拿到响应的请求
+	Elapsed   time.Duration // 总耗时
+	RPS       float64       // 每秒完成的请求数
+
+	Min  time.Duration // 最小耗时
+	Max  time.Duration // 最大耗时
+	Mean time.Duration // 平均耗时
+	P50  time.Duration // 50分位耗时
+	P90  time.Duration // 90分位耗时
+	P99  time.Duration // 99分位耗时
+
+	SlowThreshold time.Duration // 慢请求阈值
+	SlowNum       int           // 慢请求数
+
+	Histogram   []HistogramBucket // 耗时分布
+	Errors      map[string]int    // 错误数，键为错误类型
+	StatusCodes map[int]int       // 响应码分布
+
+	latencies []time.Duration // 已排序的拿到响应的请求的耗时
+}
+
+// HistogramBucket 耗时分布的一个区间，统计耗时不大于Upper的请求数；最后一个区间的Upper为0，表示不设上限
+type HistogramBucket struct {
+	Upper time.Duration
+	Count int
+}
+
+var (
+	histogramUppers = []time.Duration{
+		1 * time.Millisecond,
+		5 * time.Millisecond,
+		10 * time.Millisecond,
+		25 * time.Millisecond,
+		50 * time.Millisecond,
+		100 * time.Millisecond,
+		250 * time.Millisecond,
+		500 * time.Millisecond,
+		1 * time.Second,
+		2500 * time.Millisecond,
+		5 * time.Second,
+		10 * time.Second,
+	}
+)
+
+// Percentile 获取q分位耗时，q取值为[0, 100]
+func (r *PressureReport) Percentile(q float64) time.Duration {
+	return percentile(r.latencies, q)
+}
+
+func (r *PressureReport) String() string {
+	var b strings.Builder
+
+	b.WriteString("\n=== Pressure Report ===\n")
+	if r.Name != "" {
+		fmt.Fprintf(&b, "Stage: %s\n", r.Name)
+	}
+	fmt.Fprintf(&b, "Number: %d\nConcurrency: %d\n", r.N, r.C)
+	if r.Duration > 0 {
+		fmt.Fprintf(&b, "Duration: %v\n", r.Duration)
+	}
+	if r.Rate > 0 {
+		fmt.Fprintf(&b, "Rate: %d/s\nDropped: %d\n", r.Rate, r.Dropped)
+	}
+	fmt.Fprintf(&b, "Completed: %d\nFailed: %d\n", r.Completed, r.Failed)
+	fmt.Fprintf(&b, "Used time: %vs\nRPS: %v\n", do.Round(r.Elapsed.Seconds(), 2), do.Round(r.RPS, 2))
+	if len(r.latencies) == 0 {
+		// 全部出错时没有耗时可以统计
+		b.WriteString("Latency: no response\n")
+	} else {
+		fmt.Fprintf(&b, "Latency: min %v, mean %v, p50 %v, p90 %v, p99 %v, max %v\n", r.Min, r.Mean, r.P50, r.P90, r.P99, r.Max)
+		fmt.Fprintf(&b, "Slow(>=%v): %d\n", r.SlowThreshold, r.SlowNum)
+
+		b.WriteString("Histogram:\n")
+		for _, bucket := range r.Histogram {
+			if bucket.Count == 0 {
+				continue
+			}
+			if bucket.Upper == 0 {
+				fmt.Fprintf(&b, "  > %v: %d\n", histogramUppers[len(histogramUppers)-1], bucket.Count)
+			} else {
+				fmt.Fprintf(&b, "  <= %v: %d\n", bucket.Upper, bucket.Count)
+			}
+		}
+	}
+
+	if len(r.StatusCodes) > 0 {
+		b.WriteString("Status codes:\n")
+		codes := make([]int, 0, len(r.StatusCodes))
+		for code := range r.StatusCodes {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		for _, code := range codes {
+			fmt.Fprintf(&b, "  %d: %d\n", code, r.StatusCodes[code])
+		}
+	}
+
+	if len(r.Errors) > 0 {
+		b.WriteString("Errors:\n")
+		kinds := make([]string, 0, len(r.Errors))
+		for kind := range r.Errors {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			fmt.Fprintf(&b, "  %s: %d\n", kind, r.Errors[kind])
+		}
+	}
+	b.WriteString("=== END ===\n\n")
+
+	return b.String()
+}
+
+// pressureRecorder 并发安全地收集每个请求的结果
+type pressureRecorder struct {
+	mu sync.Mutex
+
+	slowThreshold time.Duration
+	completed     int
+	latencies     []time.Duration
+	errors        map[string]int
+	statusCodes   map[int]int
+	failed        int
+	dropped       int
+
+	sample  *exchange // 序号最小的运行
+	failure *exchange // 序号最小的出错运行
+}
+
+func newPressureRecorder(slowThreshold time.Duration) *pressureRecorder {
+	return &pressureRecorder{
+		slowThreshold: slowThreshold,
+		errors:        make(map[string]int),
+		statusCodes:   make(map[int]int),
+	}
+}
+
+func (pr *pressureRecorder) add(ex *exchange) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	if pr.sample == nil || ex.i < pr.sample.i {
+		pr.sample = ex
+	}
+
+	pr.completed++
+	// 没有拿到响应的请求耗时没有意义，不计入耗时统计
+	if ex.resp != nil {
+		pr.latencies = append(pr.latencies, ex.used)
+	}
+	if ex.err != nil {
+		pr.failed++
+		pr.errors[errorKind(ex.err)]++
+		if pr.failure == nil || ex.i < pr.failure.i {
+			pr.failure = ex
+		}
+		return
+	}
+	if ex.resp != nil {
+		pr.statusCodes[ex.resp.StatusCode]++
+	}
+}
+
+func (pr *pressureRecorder) drop() {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	pr.dropped++
+}
+
+func (pr *pressureRecorder) report(param PressureParam, elapsed time.Duration) *PressureReport {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	latencies := make([]time.Duration, len(pr.latencies))
+	copy(latencies, pr.latencies)
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	r := &PressureReport{
+		Name:          param.Name,
+		N:             param.N,
+		C:             param.C,
+		Duration:      param.Duration,
+		Rate:          param.Rate,
+		Completed:     pr.completed,
+		Dropped:       pr.dropped,
+		Failed:        pr.failed,
+		Elapsed:       elapsed,
+		SlowThreshold: pr.slowThreshold,
+		Errors:        make(map[string]int, len(pr.errors)),
+		StatusCodes:   make(map[int]int, len(pr.statusCodes)),
+		latencies:     latencies,
+	}
+	for k, v := range pr.errors {
+		r.Errors[k] = v
+	}
+	for k, v := range pr.statusCodes {
+		r.StatusCodes[k] = v
+	}
+	if elapsed > 0 {
+		r.RPS = float64(r.Completed) / elapsed.Seconds()
+	}
+
+	r.Histogram = make([]HistogramBucket, len(histogramUppers)+1)
+	for i, upper := range histogramUppers {
+		r.Histogram[i].Upper = upper
+	}
+	if len(latencies) == 0 {
+		return r
+	}
+
+	var total time.Duration
+	for _, l := range latencies {
+		total += l
+		if l >= pr.slowThreshold {
+			r.SlowNum++
+		}
+		i := sort.Search(len(histogramUppers), func(i int) bool { return l <= histogramUppers[i] })
+		r.Histogram[i].Count++
+	}
+	r.Min = latencies[0]
+	r.Max = latencies[len(latencies)-1]
+	r.Mean = total / time.Duration(len(latencies))
+	r.P50 = percentile(latencies, 50)
+	r.P90 = percentile(latencies, 90)
+	r.P99 = percentile(latencies, 99)
+
+	return r
+}
+
+// percentile 使用最近排名法计算分位数，sorted须已按升序排列
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	l := len(sorted)
+	if l == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return sorted[0]
+	}
+	if q >= 100 {
+		return sorted[l-1]
+	}
+	rank := int(math.Ceil(q/100*float64(l))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+// errorKind 错误分类
+func errorKind(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "connection refused"
+	case errors.Is(err, syscall.ECONNRESET):
+		return "connection reset"
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return "dns"
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return "net " + opErr.Op
+	}
+
+	return "other"
+}
@@ -0,0 +1,303 @@
+package apitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/donnol/apitest/testtype"
+)
+
+func TestPressureReport(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(2 * time.Millisecond)
+		w.Write([]byte(`{"code":0}`))
+	})
+
+	at := NewAT("/ok", http.MethodGet, "pressure", nil, nil).
+		SetHandler(mux).
+		SetSlowThreshold(time.Millisecond).
+		PressureRun(20, 4)
+	if err := at.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := at.PressureReport()
+	if r == nil {
+		t.Fatal("nil report")
+	}
+	if r.N != 20 || r.C != 4 || r.Completed != 20 || r.Failed != 0 {
+		t.Fatalf("bad report: %+v", r)
+	}
+	if r.StatusCodes[http.StatusOK] != 20 {
+		t.Fatalf("bad status codes: %+v", r.StatusCodes)
+	}
+	if r.Min < 2*time.Millisecond || r.Min > r.P50 || r.P50 > r.P90 || r.P90 > r.P99 || r.P99 > r.Max {
+		t.Fatalf("bad latency: %+v", r)
+	}
+	if r.Mean < r.Min || r.Mean > r.Max {
+		t.Fatalf("bad mean: %v", r.Mean)
+	}
+	if r.SlowNum != 20 {
+		t.Fatalf("bad slow number: %d", r.SlowNum)
+	}
+	var count int
+	for _, bucket := range r.Histogram {
+		count += bucket.Count
+	}
+	if count != 20 {
+		t.Fatalf("bad histogram: %+v", r.Histogram)
+	}
+	if r.RPS <= 0 {
+		t.Fatalf("bad rps: %v", r.RPS)
+	}
+}
+
+func TestPressureReportErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	at := NewAT("/", http.MethodGet, "pressure", nil, nil).
+		SetHost(u.Host).
+		PressureRunBatch([]PressureParam{{N: 3, C: 1}, {N: 4, C: 2}})
+	if at.Err() == nil {
+		t.Fatal("want error")
+	}
+
+	reports := at.PressureReports()
+	if len(reports) != 2 {
+		t.Fatalf("bad report number: %d", len(reports))
+	}
+	for i, want := range []int{3, 4} {
+		r := reports[i]
+		if r.Completed != want || r.Failed != want || r.Errors["connection refused"] != want {
+			t.Fatalf("bad report: %+v", r)
+		}
+	}
+	if at.PressureReport() != reports[1] {
+		t.Fatal("bad last report")
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i))
+	}
+	for _, tc := range []struct {
+		q    float64
+		want time.Duration
+	}{
+		{0, 1},
+		{50, 50},
+		{90, 90},
+		{99, 99},
+		{99.5, 100},
+		{100, 100},
+	} {
+		if got := percentile(sorted, tc.q); got != tc.want {
+			t.Errorf("percentile(%v) = %v, want %v", tc.q, got, tc.want)
+		}
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("percentile of empty = %v", got)
+	}
+}
+
+func TestPressureModes(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":0}`))
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+	})
+
+	t.Run("duration", func(t *testing.T) {
+		at := NewAT("/ok", http.MethodGet, "pressure", nil, nil).
+			SetHandler(mux).
+			PressureRunFor(100*time.Millisecond, 2)
+		if err := at.Err(); err != nil {
+			t.Fatal(err)
+		}
+		r := at.PressureReport()
+		if r.Duration != 100*time.Millisecond || r.Completed == 0 || r.Elapsed < r.Duration {
+			t.Fatalf("bad report: %+v", r)
+		}
+	})
+
+	t.Run("rate", func(t *testing.T) {
+		at := NewAT("/ok", http.MethodGet, "pressure", nil, nil).
+			SetHandler(mux).
+			PressureRunRate(200, 200*time.Millisecond)
+		if err := at.Err(); err != nil {
+			t.Fatal(err)
+		}
+		r := at.PressureReport()
+		if r.Rate != 200 || r.Completed != 40 || r.Dropped != 0 {
+			t.Fatalf("bad report: %+v", r)
+		}
+	})
+
+	t.Run("dropped", func(t *testing.T) {
+		at := NewAT("/slow", http.MethodGet, "pressure", nil, nil).
+			SetHandler(mux).
+			PressureRunBatch([]PressureParam{{N: 20, C: 1, Rate: 1000}})
+		if err := at.Err(); err != nil {
+			t.Fatal(err)
+		}
+		r := at.PressureReport()
+		if r.Dropped == 0 || r.Completed+r.Dropped != 20 {
+			t.Fatalf("bad report: %+v", r)
+		}
+	})
+
+	t.Run("ramp", func(t *testing.T) {
+		stages := RampStages(50, 150, 50, 100*time.Millisecond)
+		if len(stages) != 3 {
+			t.Fatalf("bad stages: %+v", stages)
+		}
+		at := NewAT("/ok", http.MethodGet, "pressure", nil, nil).
+			SetHandler(mux).
+			PressureRunBatch(stages)
+		if err := at.Err(); err != nil {
+			t.Fatal(err)
+		}
+		reports := at.PressureReports()
+		if len(reports) != 3 {
+			t.Fatalf("bad report number: %d", len(reports))
+		}
+		for i, r := range reports {
+			want := stages[i].Rate / 10
+			if r.Name != stages[i].Name || r.Rate != stages[i].Rate || r.Completed < want || r.Completed > want+1 {
+				t.Fatalf("bad report: %+v", r)
+			}
+		}
+	})
+
+	t.Run("bad param", func(t *testing.T) {
+		at := NewAT("/ok", http.MethodGet, "pressure", nil, nil).
+			SetHandler(mux).
+			PressureRunBatch([]PressureParam{{C: 1}})
+		if at.Err() == nil {
+			t.Fatal("want error")
+		}
+	})
+}
+
+func TestPressureSample(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	})
+
+	var r testtype.User
+	at := NewAT("/user", http.MethodPost, "pressure", nil, nil).
+		SetHandler(mux).
+		SetParam(&testtype.User{Id: 1, Name: "jd"}).
+		PressureRun(50, 8).
+		EqualCode(http.StatusOK).
+		Result(&r)
+	if err := at.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if at.Resp() == nil || r.Id != 1 || r.Name != "jd" {
+		t.Fatalf("bad sample: %+v", r)
+	}
+	if report := at.PressureReport(); report.Completed != 50 || report.StatusCodes[http.StatusOK] != 50 {
+		t.Fatalf("bad report: %+v", report)
+	}
+}
+
+func TestPressureRunWith(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		ids    = make(map[uint]bool)
+		tokens = make(map[string]int)
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		var p testtype.User
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		ids[p.Id] = true
+		tokens[r.Header.Get("Authorization")]++
+		mu.Unlock()
+	})
+
+	at := NewAT("/user", http.MethodPost, "pressure", http.Header{"X-Trace": []string{"1"}}, nil).
+		SetHandler(mux).
+		SetParam(&testtype.User{Id: 1}).
+		SetPressureHeader(func(i int) http.Header {
+			return http.Header{"Authorization": []string{fmt.Sprintf("Bearer user%d", i%3)}}
+		}).
+		PressureRunWith(30, 4, func(i int) any {
+			return &testtype.User{Id: uint(i + 1), Name: "jd"}
+		})
+	if err := at.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 30 {
+		t.Fatalf("bad id number: %d", len(ids))
+	}
+	if len(tokens) != 3 || tokens["Bearer user0"] != 10 {
+		t.Fatalf("bad tokens: %+v", tokens)
+	}
+	if at.req.Header.Get("Authorization") != "Bearer user0" || at.req.Header.Get("X-Trace") != "1" {
+		t.Fatalf("bad sample header: %+v", at.req.Header)
+	}
+
+	// 随机参数
+	gen := at.MonkeyParamFunc()
+	p1, ok := gen(0).(*testtype.User)
+	if !ok {
+		t.Fatalf("bad param type: %T", gen(0))
+	}
+	p2 := gen(1).(*testtype.User)
+	if p1.Name == p2.Name && p1.Id == p2.Id {
+		t.Fatalf("param is not random: %+v, %+v", p1, p2)
+	}
+}
+
+func TestMonkeyParamFuncError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {})
+
+	type badParam struct {
+		Age int `fake:"{notexist}"`
+	}
+	at := NewAT("/user", http.MethodPost, "pressure", nil, nil).
+		SetHandler(mux).
+		SetParam(&badParam{})
+	at.SetPressureParam(at.MonkeyParamFunc()).PressureRun(10, 2)
+	if err := at.Err(); err == nil || !strings.Contains(err.Error(), "generate param value failed") {
+		t.Fatalf("bad err: %v", err)
+	}
+	report := at.PressureReport()
+	if report.Failed != 10 || report.Completed != 10 {
+		t.Fatalf("bad report: %+v", report)
+	}
+	// 没有发出的请求不计入耗时
+	if report.Min != 0 || report.Max != 0 || report.Histogram[0].Count != 0 || report.Percentile(50) != 0 {
+		t.Fatalf("failed requests should not be in latency: %+v", report)
+	}
+	if s := report.String(); !strings.Contains(s, "Latency: no response") || strings.Contains(s, "Histogram") {
+		t.Fatalf("bad report:\n%s", s)
+	}
+}
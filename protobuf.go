@@ -0,0 +1,213 @@
+package apitest
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	protobufFormat      = "protobuf"
+	protobufContentType = "application/x-protobuf"
+)
+
+// UseProtobufFormat 设置参数和结果格式为protobuf，参数和结果需要实现proto.Message；文档里的示例使用protojson
+func (at *AT) UseProtobufFormat() *AT {
+	at.paramFormat = protobufFormat
+	at.resultFormat = protobufFormat
+	return at
+}
+
+func protoMarshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("protobuf param should be proto.Message, have %T", v)
+	}
+	return proto.Marshal(m)
+}
+
+func protoUnmarshal(data []byte, r any) error {
+	m, ok := r.(proto.Message)
+	if !ok {
+		return fmt.Errorf("protobuf result should be proto.Message, have %T", r)
+	}
+	if err := proto.Unmarshal(data, m); err != nil {
+		return fmt.Errorf("protobuf decode failed: %+v", err)
+	}
+	return nil
+}
+
+// protoJSON 转为protojson，用于文档里的示例
+func protoJSON(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("protobuf value should be proto.Message, have %T", v)
+	}
+	return protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(m)
+}
+
+// protoToBlock 根据消息的描述符生成文档块，字段名使用protojson的名字，注释取自proto文件里字段前的注释
+func protoToBlock(name, in string, m proto.Message) (string, map[string]string, error) {
+	data, err := protoJSON(m)
+	if err != nil {
+		return "", nil, err
+	}
+
+	lines, kcm := protoFieldsToLine(0, m.ProtoReflect().Descriptor(), nil)
+	return blockTitle(name, in, data) + lines + "\n", kcm, nil
+}
+
+func protoFieldsToLine(level int, md protoreflect.MessageDescriptor, visiting []protoreflect.FullName) (string, map[string]string) {
+	var lines string
+	var keyCommentMap = make(map[string]string)
+
+	// 递归的消息只展开一次
+	for _, name := range visiting {
+		if name == md.FullName() {
+			return lines, keyCommentMap
+		}
+	}
+	visiting = append(visiting, md.FullName())
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		fieldName := fd.JSONName()
+		fieldComment := protoComment(fd)
+
+		key := "|" + fieldName
+		keyCommentMap[key] = fieldComment
+		lines += fmt.Sprintf("%s %s (*%s*) %s\n", linePrefix(level), fieldName, protoTypeName(fd), fieldComment)
+
+		// 消息需要继续遍历，并且在写入时向内缩进
+		if fd.IsMap() || fd.Message() == nil || isProtoWellKnown(fd.Message()) {
+			continue
+		}
+		innerLines, kcm := protoFieldsToLine(level+1, fd.Message(), visiting)
+		for k, v := range kcm {
+			keyCommentMap[key+k] = v
+		}
+		lines += innerLines
+	}
+
+	return lines, keyCommentMap
+}
+
+func protoTypeName(fd protoreflect.FieldDescriptor) string {
+	if fd.IsMap() {
+		return "map"
+	}
+
+	var name string
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		name = "object"
+		if isProtoWellKnown(fd.Message()) { // protojson里为字符串
+			name = "string"
+		}
+	case protoreflect.EnumKind:
+		name = "enum"
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		name = "int32"
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		name = "int64"
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		name = "uint32"
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		name = "uint64"
+	case protoreflect.FloatKind:
+		name = "float32"
+	case protoreflect.DoubleKind:
+		name = "float64"
+	default:
+		name = fd.Kind().String() // string, bool, bytes
+	}
+	if fd.IsList() {
+		name += " list"
+	}
+	return name
+}
+
+// isProtoWellKnown 时间等类型在protojson里为字符串，不再展开
+func isProtoWellKnown(md protoreflect.MessageDescriptor) bool {
+	switch md.FullName() {
+	case "google.protobuf.Timestamp", "google.protobuf.Duration", "google.protobuf.FieldMask":
+		return true
+	}
+	return false
+}
+
+// protoComment 字段前的注释，没有时使用字段后的注释；描述符里没有源码信息时为空
+func protoComment(fd protoreflect.FieldDescriptor) string {
+	loc := fd.ParentFile().SourceLocations().ByDescriptor(fd)
+	comment := loc.LeadingComments
+	if strings.TrimSpace(comment) == "" {
+		comment = loc.TrailingComments
+	}
+	return strings.Join(strings.Fields(comment), " ")
+}
+
+// protoSchema 根据消息的描述符生成OpenAPI的schema，与protoFieldsToLine一样使用protojson的名字
+func protoSchema(md protoreflect.MessageDescriptor, visiting []protoreflect.FullName) *OpenAPISchema {
+	schema := &OpenAPISchema{Type: "object"}
+
+	// 递归的消息只展开一次
+	for _, name := range visiting {
+		if name == md.FullName() {
+			return schema
+		}
+	}
+	visiting = append(visiting, md.FullName())
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		var prop *OpenAPISchema
+		switch {
+		case fd.IsMap():
+			prop = &OpenAPISchema{Type: "object", AdditionalProperties: protoKindSchema(fd.MapValue(), visiting)}
+		case fd.IsList():
+			prop = &OpenAPISchema{Type: "array", Items: protoKindSchema(fd, visiting)}
+		default:
+			prop = protoKindSchema(fd, visiting)
+		}
+		prop.Description = protoComment(fd)
+
+		if schema.Properties == nil {
+			schema.Properties = make(map[string]*OpenAPISchema)
+		}
+		schema.Properties[fd.JSONName()] = prop
+	}
+	return schema
+}
+
+// protoKindSchema 单个值的schema，64位整数在protojson里为字符串
+func protoKindSchema(fd protoreflect.FieldDescriptor, visiting []protoreflect.FullName) *OpenAPISchema {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		if isProtoWellKnown(fd.Message()) {
+			return &OpenAPISchema{Type: "string"}
+		}
+		return protoSchema(fd.Message(), visiting)
+	case protoreflect.EnumKind:
+		return &OpenAPISchema{Type: "string"}
+	case protoreflect.BoolKind:
+		return &OpenAPISchema{Type: "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return &OpenAPISchema{Type: "integer", Format: "int32"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return &OpenAPISchema{Type: "string", Format: "int64"}
+	case protoreflect.FloatKind:
+		return &OpenAPISchema{Type: "number", Format: "float"}
+	case protoreflect.DoubleKind:
+		return &OpenAPISchema{Type: "number", Format: "double"}
+	case protoreflect.BytesKind:
+		return &OpenAPISchema{Type: "string", Format: "byte"}
+	}
+	return &OpenAPISchema{Type: "string"}
+}
@@ -0,0 +1,205 @@
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// protoUserFile 相当于：
+//
+//	message Address { string city = 1; }
+//	message User {
+//	  // 名字
+//	  string name = 1;
+//	  int64 age = 2; // 年纪
+//	  repeated string tags = 3;
+//	  // 地址
+//	  Address home_addr = 4;
+//	}
+func protoUserFile(t *testing.T) protoreflect.FileDescriptor {
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, label descriptorpb.FieldDescriptorProto_Label, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(number),
+			Type:     typ.Enum(),
+			Label:    label.Enum(),
+			JsonName: proto.String(jsonCamel(name)),
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("user.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name:  proto.String("Address"),
+				Field: []*descriptorpb.FieldDescriptorProto{field("city", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional, "")},
+			},
+			{
+				Name: proto.String("User"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional, ""),
+					field("age", 2, descriptorpb.FieldDescriptorProto_TYPE_INT64, optional, ""),
+					field("tags", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING, descriptorpb.FieldDescriptorProto_LABEL_REPEATED, ""),
+					field("home_addr", 4, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, optional, ".test.Address"),
+				},
+			},
+		},
+		SourceCodeInfo: &descriptorpb.SourceCodeInfo{
+			Location: []*descriptorpb.SourceCodeInfo_Location{
+				{Path: []int32{4, 1, 2, 0}, Span: []int32{3, 2, 17}, LeadingComments: proto.String(" 名字\n")},
+				{Path: []int32{4, 1, 2, 1}, Span: []int32{4, 2, 16}, TrailingComments: proto.String(" 年纪\n")},
+				{Path: []int32{4, 1, 2, 3}, Span: []int32{7, 2, 24}, LeadingComments: proto.String(" 地址\n")},
+			},
+		},
+	}
+	fd, err := protodesc.NewFile(fdp, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fd
+}
+
+func jsonCamel(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+	}
+	return strings.Join(parts, "")
+}
+
+func TestProtobuf(t *testing.T) {
+	userDesc := protoUserFile(t).Messages().ByName("User")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != protobufContentType {
+			http.Error(w, "bad content type", http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		user := dynamicpb.NewMessage(userDesc)
+		if err := proto.Unmarshal(data, user); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// 年纪加一后返回
+		age := userDesc.Fields().ByName("age")
+		user.Set(age, protoreflect.ValueOfInt64(user.Get(age).Int()+1))
+		data, _ = proto.Marshal(user)
+		w.Header().Set("Content-Type", protobufContentType)
+		w.Write(data)
+	})
+
+	param := dynamicpb.NewMessage(userDesc)
+	param.Set(userDesc.Fields().ByName("name"), protoreflect.ValueOfString("jd"))
+	param.Set(userDesc.Fields().ByName("age"), protoreflect.ValueOfInt64(18))
+	tags := param.Mutable(userDesc.Fields().ByName("tags")).List()
+	tags.Append(protoreflect.ValueOfString("a"))
+	addr := param.Mutable(userDesc.Fields().ByName("home_addr")).Message()
+	addr.Set(addr.Descriptor().Fields().ByName("city"), protoreflect.ValueOfString("gz"))
+
+	result := dynamicpb.NewMessage(userDesc)
+	at := NewAT("/user", http.MethodPost, "新建用户", nil, nil).
+		SetHandler(mux).
+		UseProtobufFormat().
+		SetParam(param).
+		Run().
+		EqualCode(http.StatusOK).
+		Result(result)
+	if err := at.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if age := result.Get(userDesc.Fields().ByName("age")).Int(); age != 19 {
+		t.Fatalf("bad age: %d", age)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := at.WriteFile(buf).Err(); err != nil {
+		t.Fatal(err)
+	}
+	doc := buf.String()
+	for _, want := range []string{
+		"Param - Body",
+		"* name (*string*) 名字\n",
+		"* age (*int64*) 年纪\n",
+		"* tags (*string list*) \n",
+		"* homeAddr (*object*) 地址\n",
+		"    * city (*string*) \n",
+		`"name": "jd", // 名字`,
+		`"city": "gz"`,
+		`"age": "19", // 年纪`,
+		"- Content-Type: " + protobufContentType,
+	} {
+		if !strings.Contains(doc, want) {
+			t.Fatalf("doc should contain %q, have:\n%s", want, doc)
+		}
+	}
+
+	// OpenAPI和Postman里的示例使用protojson
+	buf.Reset()
+	if err := at.WriteOpenAPI(buf).Err(); err != nil {
+		t.Fatal(err)
+	}
+	var openapi OpenAPI
+	if err := json.Unmarshal(buf.Bytes(), &openapi); err != nil {
+		t.Fatal(err)
+	}
+	op := openapi.Paths["/user"].Post
+	paramMedia := op.RequestBody.Content[protobufContentType]
+	if paramMedia == nil {
+		t.Fatalf("bad request body: %s", buf.Bytes())
+	}
+	if example, ok := paramMedia.Example.(map[string]any); !ok || example["name"] != "jd" || example["homeAddr"] == nil {
+		t.Fatalf("bad param example: %#v", paramMedia.Example)
+	}
+	if props := paramMedia.Schema.Properties; props["name"] == nil || props["name"].Description != "名字" ||
+		props["age"] == nil || props["age"].Type != "string" || props["homeAddr"] == nil || props["homeAddr"].Properties["city"] == nil {
+		t.Fatalf("bad param schema: %+v", paramMedia.Schema)
+	}
+	resultMedia := op.Responses["200"].Content[protobufContentType]
+	if resultMedia == nil {
+		t.Fatalf("bad response: %s", buf.Bytes())
+	}
+	if example, ok := resultMedia.Example.(map[string]any); !ok || example["age"] != "19" {
+		t.Fatalf("bad result example: %#v", resultMedia.Example)
+	}
+
+	buf.Reset()
+	if err := at.WritePostman(buf).Err(); err != nil {
+		t.Fatal(err)
+	}
+	var collection PostmanCollection
+	if err := json.Unmarshal(buf.Bytes(), &collection); err != nil {
+		t.Fatal(err)
+	}
+	body := collection.Item[0].Request.Body
+	if body == nil || body.Options.Raw.Language != "json" || !strings.Contains(body.Raw, `"name":"jd"`) {
+		t.Fatalf("bad postman body: %+v", body)
+	}
+}
+
+func TestProtobufError(t *testing.T) {
+	at := NewAT("/user", http.MethodPost, "新建用户", nil, nil).
+		UseProtobufFormat().
+		SetParam(&struct{}{})
+	at.Curl()
+	if err := at.Err(); err == nil || !strings.Contains(err.Error(), "should be proto.Message") {
+		t.Fatalf("bad error: %v", err)
+	}
+}
@@ -0,0 +1,352 @@
+package apitest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+type (
+	// Scenario 多步骤场景，按顺序执行每一步，后面的步骤可以使用模板引用前面步骤的响应，如：{{.login.data.token}}
+	Scenario struct {
+		name   string
+		steps  []*scenarioStep
+		values map[string]any // 步骤名 -> 解析后的响应体
+
+		ran []*scenarioStep // 已执行的步骤
+		err error
+	}
+
+	scenarioStep struct {
+		name  string
+		at    *AT
+		build func(s *Scenario) *AT // 执行时才构造AT，用于需要非字符串值的情况
+		then  []func(*AT) error
+
+		refs []string          // 用到的模板，用于文档
+		tmpl *scenarioTemplate // Step传入的AT渲染前的值，重复执行时从这里重新渲染
+	}
+
+	// scenarioTemplate 可以使用模板的字段
+	scenarioTemplate struct {
+		path            string
+		authHeaderValue string
+		header          http.Header
+		param           any
+	}
+)
+
+// NewScenario 新建场景
+func NewScenario(name string) *Scenario {
+	return &Scenario{
+		name:   name,
+		values: make(map[string]any),
+	}
+}
+
+// Step 添加步骤，at的路径、header、认证信息和参数里的字符串均可使用模板，执行之后依次运行then，任一返回错误时场景停止；
+// 步骤名用作模板里的键，如：{{.login.data.token}}，名字不是标识符时使用{{index . "create-book" "data" "id"}}
+func (s *Scenario) Step(name string, at *AT, then ...func(*AT) error) *Scenario {
+	s.steps = append(s.steps, &scenarioStep{name: name, at: at, then: then})
+	return s
+}
+
+// StepFunc 添加步骤，执行到该步骤时才调用build构造AT，可以在build里使用Value获取前面步骤的值
+func (s *Scenario) StepFunc(name string, build func(s *Scenario) *AT, then ...func(*AT) error) *Scenario {
+	s.steps = append(s.steps, &scenarioStep{name: name, build: build, then: then})
+	return s
+}
+
+// Value 获取已执行步骤的响应里的值，如：Value("login.data.token")
+func (s *Scenario) Value(path string) (any, error) {
+	tokens, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 || !tokens[0].isKey {
+		return nil, fmt.Errorf("bad path %q, should start with step name", path)
+	}
+	root, ok := s.values[tokens[0].key]
+	if !ok {
+		return nil, fmt.Errorf("step %q has not run", tokens[0].key)
+	}
+	rest := strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	rest = strings.TrimPrefix(rest, tokens[0].key)
+	if rest == "" {
+		return root, nil
+	}
+	return lookupPath(root, rest, false)
+}
+
+// Run 按顺序执行步骤，遇到第一个错误时停止；重复执行时每次都从模板重新渲染
+func (s *Scenario) Run() *Scenario {
+	if s.err != nil {
+		return s
+	}
+
+	// 重复执行时不使用上一次的结果
+	s.ran = nil
+	s.values = make(map[string]any)
+	for i, step := range s.steps {
+		if err := s.runStep(step); err != nil {
+			s.err = fmt.Errorf("scenario %q step %d %q failed: %w", s.name, i+1, step.name, err)
+			return s
+		}
+	}
+
+	return s
+}
+
+func (s *Scenario) runStep(step *scenarioStep) error {
+	at := step.at
+	if step.build != nil {
+		at = step.build(s)
+		if at == nil {
+			return fmt.Errorf("nil AT")
+		}
+	}
+	step.at = at
+	if at.err != nil {
+		return at.err
+	}
+
+	if step.build == nil {
+		if step.tmpl == nil {
+			step.tmpl = &scenarioTemplate{
+				path:            at.path,
+				authHeaderValue: at.authHeaderValue,
+				header:          at.header,
+				param:           at.param,
+			}
+		}
+		at.path = step.tmpl.path
+		at.authHeaderValue = step.tmpl.authHeaderValue
+		at.header = step.tmpl.header
+		at.param = step.tmpl.param
+	}
+	at.doc = ""
+
+	r := &scenarioRenderer{data: s.values}
+	at.path = r.render(at.path)
+	at.authHeaderValue = r.render(at.authHeaderValue)
+	if at.header != nil {
+		header := make(http.Header, len(at.header))
+		for k, v := range at.header {
+			for _, vv := range v {
+				header.Add(k, r.render(vv))
+			}
+		}
+		at.header = header
+	}
+	if at.param != nil {
+		at.param = r.renderValue(reflect.ValueOf(at.param)).Interface()
+	}
+	if r.err != nil {
+		return r.err
+	}
+	step.refs = r.refs
+
+	s.ran = append(s.ran, step)
+	if err := at.Run().Err(); err != nil {
+		return err
+	}
+	for _, f := range step.then {
+		if err := f(at); err != nil {
+			return err
+		}
+	}
+	if err := at.Err(); err != nil {
+		return err
+	}
+
+	data, _, err := copyResponseBody(at.resp)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(data)) > 0 {
+		// 响应不是json或xml时，不能被后面的步骤引用
+		if v, err := decodeTree(at.resultFormat, data); err == nil {
+			s.values[step.name] = v
+		}
+	}
+
+	return nil
+}
+
+// Err 获取错误
+func (s *Scenario) Err() error {
+	return s.err
+}
+
+// CatalogEntry 目录
+func (s *Scenario) CatalogEntry() CatalogEntry {
+	keys := make([]string, 0, len(s.steps))
+	for _, step := range s.steps {
+		if step.at != nil {
+			keys = append(keys, step.at.path)
+		}
+	}
+	return CatalogEntry{
+		Title:  s.name,
+		Method: "Scenario",
+		Path:   strings.Join(keys, " → "),
+	}
+}
+
+// WriteFile 将整个流程写入markdown文件：先列出步骤及其引用的值，再依次写入每一步的文档
+func (s *Scenario) WriteFile(w io.Writer) *Scenario {
+	if w == nil {
+		s.setErr(fmt.Errorf("nil writer"))
+		return s
+	}
+	if len(s.ran) == 0 {
+		s.setErr(fmt.Errorf("empty scenario %q, please Run first", s.name))
+		return s
+	}
+
+	doc := "## " + toAnchor(s.name) + "\n\n"
+	doc += "Flow:\n\n"
+	for i, step := range s.ran {
+		doc += fmt.Sprintf("%d. %s `%s`", i+1, step.at.commentWithStatus(), apiKey(step.at.path, step.at.method))
+		if len(step.refs) > 0 {
+			doc += " <- `" + strings.Join(step.refs, "`, `") + "`"
+		}
+		doc += "\n"
+	}
+	doc += "\n"
+
+	for _, step := range s.ran {
+		at := step.at
+		if at.req == nil {
+			continue
+		}
+		if at.result == nil {
+			s.setErr(fmt.Errorf("step %q has no result for doc, please call Result in then", step.name))
+			return s
+		}
+		if at.doc == "" {
+			at.makeDoc()
+		}
+		if err := at.Err(); err != nil {
+			s.setErr(err)
+			return s
+		}
+		// 每一步作为场景下的子标题
+		doc += "#" + at.doc
+	}
+
+	if _, err := w.Write([]byte(doc)); err != nil {
+		s.setErr(err)
+		return s
+	}
+	return s
+}
+
+func (s *Scenario) setErr(err error) *Scenario {
+	if s.err == nil {
+		s.err = err
+	}
+	return s
+}
+
+var templateRefRegexp = regexp.MustCompile(`{{.*?}}`)
+
+// scenarioRenderer 使用已执行步骤的响应渲染模板
+type scenarioRenderer struct {
+	data map[string]any
+	refs []string
+	err  error
+}
+
+func (r *scenarioRenderer) render(s string) string {
+	if r.err != nil || !strings.Contains(s, "{{") {
+		return s
+	}
+
+	tmpl, err := template.New("step").Option("missingkey=error").Parse(s)
+	if err != nil {
+		r.err = err
+		return s
+	}
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, r.data); err != nil {
+		r.err = err
+		return s
+	}
+	for _, ref := range templateRefRegexp.FindAllString(s, -1) {
+		found := false
+		for _, have := range r.refs {
+			if have == ref {
+				found = true
+				break
+			}
+		}
+		if !found {
+			r.refs = append(r.refs, ref)
+		}
+	}
+	return buf.String()
+}
+
+// renderValue 复制一份参数，并渲染其中的字符串，非导出字段原样保留
+func (r *scenarioRenderer) renderValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(r.render(v.String())).Convert(v.Type())
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		n := reflect.New(v.Type().Elem())
+		n.Elem().Set(r.renderValue(v.Elem()))
+		return n
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		n := reflect.New(v.Type()).Elem()
+		n.Set(r.renderValue(v.Elem()))
+		return n
+	case reflect.Struct:
+		n := reflect.New(v.Type()).Elem()
+		n.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
+			n.Field(i).Set(r.renderValue(v.Field(i)))
+		}
+		return n
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		n := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			n.Index(i).Set(r.renderValue(v.Index(i)))
+		}
+		return n
+	case reflect.Array:
+		n := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			n.Index(i).Set(r.renderValue(v.Index(i)))
+		}
+		return n
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		n := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			n.SetMapIndex(iter.Key(), r.renderValue(iter.Value()))
+		}
+		return n
+	}
+	return v
+}
@@ -0,0 +1,181 @@
+package apitest
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type (
+	scenarioResult struct {
+		Code int            `json:"code"`
+		Data map[string]any `json:"data"`
+	}
+	scenarioLogin struct {
+		Name string `json:"name"`
+	}
+	scenarioBook struct {
+		Id    string `json:"id"`
+		Title string `json:"title"`
+	}
+	scenarioBookQuery struct {
+		Id string `json:"id"`
+	}
+)
+
+func scenarioMux() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write([]byte(`{"code":0,"data":{"token":"abc"}}`))
+	})
+	mux.HandleFunc("/book", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if r.Header.Get("Authorization") != "abc" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code":1,"msg":"unauthorized"}`))
+			return
+		}
+		switch r.Method {
+		case http.MethodPost:
+			w.Write([]byte(`{"code":0,"data":{"id":7}}`))
+		default:
+			fmt.Fprintf(w, `{"code":0,"data":{"id":%s,"title":"go"}}`, r.URL.Query().Get("id"))
+		}
+	})
+	return mux
+}
+
+func TestScenario(t *testing.T) {
+	mux := scenarioMux()
+	header := http.Header{"Authorization": []string{"{{.login.data.token}}"}}
+
+	s := NewScenario("书本流程").
+		Step("login", NewAT("/login", http.MethodPost, "登录", nil, nil).SetHandler(mux).SetParam(&scenarioLogin{Name: "jd"}),
+			func(at *AT) error {
+				return at.Result(&scenarioResult{}).Err()
+			}).
+		Step("create", NewAT("/book", http.MethodPost, "新建书本", header, nil).SetHandler(mux).
+			MarkAuthHeader("Authorization", "{{.login.data.token}}").
+			SetParam(&scenarioBook{Title: "go"}),
+			func(at *AT) error {
+				return at.EqualCode(http.StatusOK).Result(&scenarioResult{}).Err()
+			}).
+		StepFunc("get", func(s *Scenario) *AT {
+			id, err := s.Value("create.data.id")
+			if err != nil {
+				t.Fatal(err)
+			}
+			return NewAT("/book", http.MethodGet, "获取书本", header, nil).SetHandler(mux).
+				SetParam(&scenarioBookQuery{Id: fmt.Sprint(id)})
+		}, func(at *AT) error {
+			return at.EqualPath("data.title", "go").Result(&scenarioResult{}).Err()
+		}).
+		Run()
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := s.WriteFile(buf).Err(); err != nil {
+		t.Fatal(err)
+	}
+	doc := buf.String()
+	for _, want := range []string{
+		"## " + toAnchor("书本流程"),
+		"1. 登录 `POST /login`\n",
+		"2. 新建书本 `POST /book` <- `{{.login.data.token}}`\n",
+		"3. 获取书本 `GET /book` <- `{{.login.data.token}}`\n",
+		"### " + toAnchor("新建书本"),
+		"- Authorization: abc",
+	} {
+		if !strings.Contains(doc, want) {
+			t.Fatalf("doc should contain %q, have:\n%s", want, doc)
+		}
+	}
+
+	if entry := s.CatalogEntry(); entry.Path != "/login → /book → /book" {
+		t.Fatalf("bad catalog entry: %+v", entry)
+	}
+}
+
+func TestScenarioStopOnFailure(t *testing.T) {
+	mux := scenarioMux()
+
+	called := false
+	s := NewScenario("未登录").
+		Step("create", NewAT("/book", http.MethodPost, "新建书本", nil, nil).SetHandler(mux).SetParam(&scenarioBook{Title: "go"}),
+			func(at *AT) error {
+				return at.EqualCode(http.StatusOK).Err()
+			}).
+		Step("get", NewAT("/book", http.MethodGet, "获取书本", nil, nil).SetHandler(mux),
+			func(at *AT) error {
+				called = true
+				return nil
+			}).
+		Run()
+	if err := s.Err(); err == nil || !strings.Contains(err.Error(), `step 1 "create" failed`) {
+		t.Fatalf("bad error: %v", err)
+	}
+	if called {
+		t.Fatal("should stop on first failure")
+	}
+
+	// 引用不存在的值
+	s = NewScenario("bad ref").
+		Step("get", NewAT("/book", http.MethodGet, "获取书本", http.Header{"Authorization": []string{"{{.login.data.token}}"}}, nil).SetHandler(mux)).
+		Run()
+	if err := s.Err(); err == nil || !strings.Contains(err.Error(), "login") {
+		t.Fatalf("bad error: %v", err)
+	}
+}
+
+func TestScenarioRunTwice(t *testing.T) {
+	// 每次登录得到新的token，旧的token失效
+	var token int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		token++
+		fmt.Fprintf(w, `{"code":0,"data":{"token":"t%d"}}`, token)
+	})
+	mux.HandleFunc("/book", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != fmt.Sprintf("t%d", token) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+		w.Write([]byte(`{"code":0,"data":{"id":7}}`))
+	})
+
+	header := http.Header{"Authorization": []string{"{{.login.data.token}}"}}
+	book := &scenarioBook{Title: "{{.login.data.token}}"}
+	s := NewScenario("重复执行").
+		Step("login", NewAT("/login", http.MethodPost, "登录", nil, nil).SetHandler(mux).SetParam(&scenarioLogin{Name: "jd"}),
+			func(at *AT) error {
+				return at.Result(&scenarioResult{}).Err()
+			}).
+		Step("create", NewAT("/book", http.MethodPost, "新建书本", header, nil).SetHandler(mux).SetParam(book),
+			func(at *AT) error {
+				return at.EqualCode(http.StatusOK).Result(&scenarioResult{}).Err()
+			})
+	for i := 0; i < 2; i++ {
+		if err := s.Run().Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if book.Title != "{{.login.data.token}}" || header.Get("Authorization") != "{{.login.data.token}}" {
+		t.Fatalf("template should be kept, have %q, %q", book.Title, header.Get("Authorization"))
+	}
+
+	buf := new(bytes.Buffer)
+	if err := s.WriteFile(buf).Err(); err != nil {
+		t.Fatal(err)
+	}
+	doc := buf.String()
+	if n := strings.Count(doc, "新建书本 `POST /book`"); n != 1 {
+		t.Fatalf("flow should be listed once, have %d:\n%s", n, doc)
+	}
+	if !strings.Contains(doc, `"title": "t2"`) {
+		t.Fatalf("doc should use the last run, have:\n%s", doc)
+	}
+}
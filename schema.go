@@ -0,0 +1,489 @@
+package apitest
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Strict 开启严格模式：Result解析json响应时，响应里有结果类型没有的字段、缺少非omitempty字段或者字段类型不匹配时报错
+func (at *AT) Strict() *AT {
+	at.strict = true
+	return at
+}
+
+// ValidateSchema 使用JSON Schema文件校验json响应
+func (at *AT) ValidateSchema(file string) *AT {
+	data, _, err := copyResponseBody(at.resp)
+	if err != nil {
+		at.setErr(err)
+		return at
+	}
+
+	schemaData, err := os.ReadFile(file)
+	if err != nil {
+		at.setErr(fmt.Errorf("read schema file failed: %w", err))
+		return at
+	}
+	var schema any
+	if err := json.Unmarshal(schemaData, &schema); err != nil {
+		at.setErr(fmt.Errorf("parse schema file %s failed: %w", file, err))
+		return at
+	}
+
+	node, err := decodeTree("json", data)
+	if err != nil {
+		at.setErr(err)
+		return at
+	}
+
+	v := &schemaValidator{root: schema}
+	v.validate("$", schema, node)
+	if len(v.problems) > 0 {
+		at.setErr(fmt.Errorf("response doesn't match schema %s:\n%s", file, strings.Join(v.problems, "\n")))
+		return at
+	}
+
+	return at
+}
+
+// checkStrict 以结果类型校验json数据
+func checkStrict(data []byte, r any) error {
+	node, err := decodeTree("json", data)
+	if err != nil {
+		return err
+	}
+
+	typ := reflect.TypeOf(r)
+	if typ == nil {
+		return nil
+	}
+
+	var problems []string
+	strictValue("$", typ, node, &problems)
+	if len(problems) > 0 {
+		return fmt.Errorf("response doesn't match %s:\n%s", typ, strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+func strictValue(path string, typ reflect.Type, node any, problems *[]string) {
+	mismatch := func(want string) {
+		*problems = append(*problems, fmt.Sprintf("%s: type mismatch, have %s, want %s", path, fragment(node), want))
+	}
+
+	if node == nil {
+		switch typ.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+		default:
+			mismatch(typ.String())
+		}
+		return
+	}
+
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	// 自定义了解析方法的类型，如time.Time，不再深入校验
+	if reflect.PointerTo(typ).Implements(jsonUnmarshalerType) ||
+		reflect.PointerTo(typ).Implements(textUnmarshalerType) {
+		return
+	}
+
+	switch typ.Kind() {
+	case reflect.Interface:
+	case reflect.String:
+		if _, ok := node.(string); !ok {
+			mismatch("string")
+		}
+	case reflect.Bool:
+		if _, ok := node.(bool); !ok {
+			mismatch("bool")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := node.(json.Number)
+		if !ok {
+			mismatch(typ.Kind().String())
+			return
+		}
+		// 按字段实际的位数校验范围，如int8不能接收300
+		var err error
+		switch typ.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			_, err = strconv.ParseInt(n.String(), 10, typ.Bits())
+		default:
+			_, err = strconv.ParseUint(n.String(), 10, typ.Bits())
+		}
+		if err != nil {
+			mismatch(typ.Kind().String())
+		}
+	case reflect.Float32, reflect.Float64:
+		if _, ok := node.(json.Number); !ok {
+			mismatch(typ.Kind().String())
+		}
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 { // []byte编码为base64字符串
+			if _, ok := node.(string); ok {
+				return
+			}
+		}
+		list, ok := node.([]any)
+		if !ok {
+			mismatch("array")
+			return
+		}
+		for i, item := range list {
+			strictValue(fmt.Sprintf("%s[%d]", path, i), typ.Elem(), item, problems)
+		}
+	case reflect.Map:
+		m, ok := node.(map[string]any)
+		if !ok {
+			mismatch("object")
+			return
+		}
+		keys := sortedKeys(m)
+		for _, k := range keys {
+			strictValue(path+"."+k, typ.Elem(), m[k], problems)
+		}
+	case reflect.Struct:
+		m, ok := node.(map[string]any)
+		if !ok {
+			mismatch("object")
+			return
+		}
+		strictStruct(path, typ, m, problems)
+	}
+}
+
+func strictStruct(path string, typ reflect.Type, m map[string]any, problems *[]string) {
+	known := make(map[string]bool)
+	for _, field := range strictFields(typ) {
+		name := field.name
+		known[name] = true
+
+		v, ok := m[name]
+		if !ok {
+			if !field.hasOpt("omitempty") {
+				*problems = append(*problems, fmt.Sprintf("%s.%s: missing field", path, name))
+			}
+			continue
+		}
+
+		if field.hasOpt("string") { // `json:",string"`的字段以字符串传输
+			if _, ok := v.(string); !ok {
+				*problems = append(*problems, fmt.Sprintf("%s.%s: type mismatch, have %s, want string", path, name, fragment(v)))
+			}
+			continue
+		}
+		strictValue(path+"."+name, field.typ, v, problems)
+	}
+
+	for _, k := range sortedKeys(m) {
+		if !known[k] {
+			*problems = append(*problems, fmt.Sprintf("%s.%s: unknown field, value is %s", path, k, fragment(m[k])))
+		}
+	}
+}
+
+type strictField struct {
+	docField
+	typ reflect.Type
+}
+
+// strictFields 获取结构体的字段，字段名和内嵌结构体的规则由resolveDocField决定，与文档里列出的字段一致
+func strictFields(typ reflect.Type) []strictField {
+	if v, ok := strictFieldCache.Load(typ); ok {
+		return v.([]strictField)
+	}
+
+	var fields []strictField
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		df, ok := resolveDocField(field)
+		if !ok {
+			continue
+		}
+
+		ftype := field.Type
+		if df.embed {
+			if ftype.Kind() == reflect.Ptr {
+				ftype = ftype.Elem()
+			}
+			fields = append(fields, strictFields(ftype)...)
+			continue
+		}
+
+		fields = append(fields, strictField{docField: df, typ: ftype})
+	}
+
+	strictFieldCache.Store(typ, fields)
+	return fields
+}
+
+var strictFieldCache sync.Map
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// schemaValidator 支持JSON Schema的常用关键字：type、properties、required、additionalProperties、items、enum、const、
+// minimum、maximum、exclusiveMinimum、exclusiveMaximum、minLength、maxLength、pattern、minItems、maxItems、
+// allOf、anyOf、oneOf、not，以及指向本文件的$ref
+type schemaValidator struct {
+	root     any
+	problems []string
+}
+
+func (v *schemaValidator) addf(path, format string, args ...any) {
+	v.problems = append(v.problems, path+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *schemaValidator) validate(path string, schema any, node any) {
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			v.addf(path, "not allowed")
+		}
+		return
+	case map[string]any:
+		v.validateObject(path, s, node)
+	}
+}
+
+func (v *schemaValidator) validateObject(path string, schema map[string]any, node any) {
+	if ref, ok := schema["$ref"].(string); ok {
+		target, err := v.resolveRef(ref)
+		if err != nil {
+			v.addf(path, "%v", err)
+			return
+		}
+		v.validate(path, target, node)
+	}
+
+	if t, ok := schema["type"]; ok {
+		var types []string
+		switch tv := t.(type) {
+		case string:
+			types = []string{tv}
+		case []any:
+			for _, item := range tv {
+				if s, ok := item.(string); ok {
+					types = append(types, s)
+				}
+			}
+		}
+		matched := false
+		for _, typ := range types {
+			if schemaTypeMatch(typ, node) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.addf(path, "type mismatch, have %s, want %s", fragment(node), strings.Join(types, " or "))
+			return
+		}
+	}
+
+	if enum, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			if equalSchemaValue(node, e) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.addf(path, "value %s not in enum %s", fragment(node), fragment(enum))
+		}
+	}
+	if c, ok := schema["const"]; ok && !equalSchemaValue(node, c) {
+		v.addf(path, "value %s not equal to const %s", fragment(node), fragment(c))
+	}
+
+	switch n := node.(type) {
+	case json.Number:
+		f, _ := n.Float64()
+		if min, ok := schemaNumber(schema, "minimum"); ok && f < min {
+			v.addf(path, "%v is less than minimum %v", n, min)
+		}
+		if max, ok := schemaNumber(schema, "maximum"); ok && f > max {
+			v.addf(path, "%v is greater than maximum %v", n, max)
+		}
+		if min, ok := schemaNumber(schema, "exclusiveMinimum"); ok && f <= min {
+			v.addf(path, "%v is not greater than exclusiveMinimum %v", n, min)
+		}
+		if max, ok := schemaNumber(schema, "exclusiveMaximum"); ok && f >= max {
+			v.addf(path, "%v is not less than exclusiveMaximum %v", n, max)
+		}
+	case string:
+		l := float64(utf8.RuneCountInString(n))
+		if min, ok := schemaNumber(schema, "minLength"); ok && l < min {
+			v.addf(path, "length of %q is less than minLength %v", n, min)
+		}
+		if max, ok := schemaNumber(schema, "maxLength"); ok && l > max {
+			v.addf(path, "length of %q is greater than maxLength %v", n, max)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				v.addf(path, "bad pattern %q: %v", pattern, err)
+			} else if !re.MatchString(n) {
+				v.addf(path, "%q doesn't match pattern %q", n, pattern)
+			}
+		}
+	case []any:
+		l := float64(len(n))
+		if min, ok := schemaNumber(schema, "minItems"); ok && l < min {
+			v.addf(path, "item number %v is less than minItems %v", l, min)
+		}
+		if max, ok := schemaNumber(schema, "maxItems"); ok && l > max {
+			v.addf(path, "item number %v is greater than maxItems %v", l, max)
+		}
+		if items, ok := schema["items"]; ok {
+			for i, item := range n {
+				v.validate(fmt.Sprintf("%s[%d]", path, i), items, item)
+			}
+		}
+	case map[string]any:
+		props, _ := schema["properties"].(map[string]any)
+		if required, ok := schema["required"].([]any); ok {
+			for _, r := range required {
+				name, _ := r.(string)
+				if _, ok := n[name]; !ok {
+					v.addf(path+"."+name, "missing required property")
+				}
+			}
+		}
+		for _, k := range sortedKeys(n) {
+			if ps, ok := props[k]; ok {
+				v.validate(path+"."+k, ps, n[k])
+				continue
+			}
+			if additional, ok := schema["additionalProperties"]; ok {
+				if b, ok := additional.(bool); ok && !b {
+					v.addf(path+"."+k, "additional property is not allowed")
+					continue
+				}
+				v.validate(path+"."+k, additional, n[k])
+			}
+		}
+	}
+
+	if all, ok := schema["allOf"].([]any); ok {
+		for _, sub := range all {
+			v.validate(path, sub, node)
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]any); ok {
+		if v.countMatch(path, anyOf, node) == 0 {
+			v.addf(path, "%s doesn't match any schema of anyOf", fragment(node))
+		}
+	}
+	if oneOf, ok := schema["oneOf"].([]any); ok {
+		if n := v.countMatch(path, oneOf, node); n != 1 {
+			v.addf(path, "%s matches %d schemas of oneOf, want 1", fragment(node), n)
+		}
+	}
+	if not, ok := schema["not"]; ok {
+		if v.countMatch(path, []any{not}, node) == 1 {
+			v.addf(path, "%s should not match schema of not", fragment(node))
+		}
+	}
+}
+
+func (v *schemaValidator) countMatch(path string, schemas []any, node any) int {
+	var n int
+	for _, sub := range schemas {
+		sv := &schemaValidator{root: v.root}
+		sv.validate(path, sub, node)
+		if len(sv.problems) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// resolveRef 解析本文件内的引用，如："#/definitions/user"、"#/$defs/user"
+func (v *schemaValidator) resolveRef(ref string) (any, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("not support $ref %q", ref)
+	}
+	cur := v.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#"), "/") {
+		if part == "" {
+			continue
+		}
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("bad $ref %q", ref)
+		}
+		cur, ok = m[part]
+		if !ok {
+			return nil, fmt.Errorf("bad $ref %q", ref)
+		}
+	}
+	return cur, nil
+}
+
+func schemaTypeMatch(typ string, node any) bool {
+	switch typ {
+	case "null":
+		return node == nil
+	case "boolean":
+		_, ok := node.(bool)
+		return ok
+	case "string":
+		_, ok := node.(string)
+		return ok
+	case "number":
+		_, ok := node.(json.Number)
+		return ok
+	case "integer":
+		n, ok := node.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := n.Float64()
+		return err == nil && f == math.Trunc(f)
+	case "array":
+		_, ok := node.([]any)
+		return ok
+	case "object":
+		_, ok := node.(map[string]any)
+		return ok
+	}
+	return false
+}
+
+func schemaNumber(schema map[string]any, key string) (float64, bool) {
+	f, ok := schema[key].(float64)
+	return f, ok
+}
+
+// equalSchemaValue schema使用json.Unmarshal解析，数字为float64；node的数字为json.Number
+func equalSchemaValue(node, want any) bool {
+	return equalPathValue(node, want, false)
+}
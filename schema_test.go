@@ -0,0 +1,134 @@
+package apitest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type strictBase struct {
+	ID int64 `json:"id"`
+}
+
+type strictUser struct {
+	strictBase
+
+	Name      string     `json:"name"`
+	Age       int        `json:"age,string"`
+	Tags      []string   `json:"tags,omitempty"`
+	CreatedAt *time.Time `json:"createdAt"`
+	Extra     any        `json:"extra,omitempty"`
+	Level     int8       `json:"level,omitempty"`
+}
+
+type strictResult struct {
+	Code int        `json:"code"`
+	Msg  string     `json:"msg"`
+	Data strictUser `json:"data"`
+}
+
+func strictMux(body string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	})
+	return mux
+}
+
+func TestStrict(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"ok", `{"code":0,"msg":"","data":{"id":1,"name":"jd","age":"18","createdAt":"2023-01-01T00:00:00Z","extra":{"a":1}}}`, nil},
+		{"null pointer", `{"code":0,"msg":"","data":{"id":1,"name":"jd","age":"18","tags":["a"],"createdAt":null}}`, nil},
+		{"unknown field", `{"code":0,"msg":"","data":{"id":1,"name":"jd","age":"18","createdAt":null,"nick":"j"}}`, []string{`$.data.nick: unknown field, value is "j"`}},
+		{"missing field", `{"code":0,"data":{"name":"jd","age":"18","createdAt":null}}`, []string{"$.msg: missing field", "$.data.id: missing field"}},
+		{"type mismatch", `{"code":"0","msg":"","data":{"id":1.5,"name":"jd","age":18,"tags":[1],"createdAt":null}}`, []string{
+			`$.code: type mismatch, have "0", want int`,
+			"$.data.id: type mismatch, have 1.5, want int64",
+			"$.data.age: type mismatch, have 18, want string",
+			"$.data.tags[0]: type mismatch, have 1, want string",
+		}},
+		{"out of range", `{"code":0,"msg":"","data":{"id":1,"name":"jd","age":"18","createdAt":null,"level":300}}`, []string{
+			"$.data.level: type mismatch, have 300, want int8",
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var r strictResult
+			err := NewAT("/user", http.MethodGet, "strict", nil, nil).
+				SetHandler(strictMux(tc.body)).
+				Strict().
+				Run().
+				Result(&r).
+				Err()
+			if len(tc.want) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("want error, but got nil")
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q doesn't contain %q", err, want)
+				}
+			}
+		})
+	}
+
+	// 非严格模式下保持原有的宽松解析
+	var r strictResult
+	if err := NewAT("/user", http.MethodGet, "strict", nil, nil).
+		SetHandler(strictMux(`{"code":0,"nick":"j"}`)).
+		Run().
+		Result(&r).
+		Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"ok", `{"code":0,"msg":"","data":{"id":1,"name":"jd","tags":["a"],"createdAt":null}}`, nil},
+		{"bad", `{"code":1,"data":{"id":0,"name":"JD","tags":["a","b",3]},"extra":true}`, []string{
+			"$.msg: missing required property",
+			"$.code: value 1 not in enum [0]",
+			"$.data.id: 0 is less than minimum 1",
+			`$.data.name: "JD" doesn't match pattern "^[a-z]+$"`,
+			"$.data.tags: item number 3 is greater than maxItems 2",
+			"$.data.tags[2]: type mismatch, have 3, want string",
+			"$.extra: additional property is not allowed",
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := NewAT("/user", http.MethodGet, "schema", nil, nil).
+				SetHandler(strictMux(tc.body)).
+				Run().
+				ValidateSchema("testdata/user.schema.json").
+				Err()
+			if len(tc.want) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("want error, but got nil")
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q doesn't contain %q", err, want)
+				}
+			}
+		})
+	}
+}
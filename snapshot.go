@@ -0,0 +1,118 @@
+package apitest
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	snapshotDir       = "testdata/snapshots"
+	snapshotUpdateEnv = "APITEST_UPDATE"
+)
+
+// UpdateSnapshots 为true时，MatchSnapshot使用当前响应重写快照；
+// 也可以设置环境变量APITEST_UPDATE=1，或者在测试里定义-update参数后使用`go test -update`
+var UpdateSnapshots bool
+
+// MatchSnapshot 将格式化后的响应体与testdata/snapshots目录下的快照比较，快照不存在时自动创建；
+// ignorePaths为不参与比较的路径，如："traceId"、"timestamp"、"data.list[*].createdAt"
+func (at *AT) MatchSnapshot(name string, ignorePaths ...string) *AT {
+	if at.resp == nil {
+		at.setErr(fmt.Errorf("no response for snapshot %s, please Run first", name))
+		return at
+	}
+	data, _, err := copyResponseBody(at.resp)
+	if err != nil {
+		at.setErr(err)
+		return at
+	}
+
+	format := at.resultFormat
+	have, err := decodeTree(format, data)
+	if err != nil {
+		at.setErr(err)
+		return at
+	}
+
+	ext := ".json"
+	if format == "xml" {
+		ext = ".xml"
+	}
+	file := filepath.Join(snapshotDir, name+ext)
+
+	if _, err := os.Stat(file); os.IsNotExist(err) || shouldUpdateSnapshots() {
+		if err := writeSnapshot(file, format, data); err != nil {
+			at.setErr(err)
+			return at
+		}
+		log.Printf("Write snapshot %s\n", file)
+		return at
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		at.setErr(err)
+		return at
+	}
+	want, err := decodeTree(format, content)
+	if err != nil {
+		at.setErr(fmt.Errorf("bad snapshot %s: %w", file, err))
+		return at
+	}
+
+	for _, path := range ignorePaths {
+		if err := removePath(have, path); err != nil {
+			at.setErr(err)
+			return at
+		}
+		if err := removePath(want, path); err != nil {
+			at.setErr(err)
+			return at
+		}
+	}
+
+	if diffs := diffTree("", have, want); len(diffs) > 0 {
+		at.setErr(fmt.Errorf("response doesn't match snapshot %s, set %s=1 to update:\n%s", file, snapshotUpdateEnv, strings.Join(diffs, "\n")))
+		return at
+	}
+
+	return at
+}
+
+// writeSnapshot 使用JSONIndent或XMLIndent格式化后保存
+func writeSnapshot(file, format string, data []byte) error {
+	buf := new(bytes.Buffer)
+	switch format {
+	case "xml":
+		XMLIndent(buf, data)
+	default:
+		JSONIndent(buf, data)
+	}
+	buf.WriteString("\n")
+
+	if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(file, buf.Bytes(), 0o644)
+}
+
+func shouldUpdateSnapshots() bool {
+	if UpdateSnapshots {
+		return true
+	}
+	if v, err := strconv.ParseBool(os.Getenv(snapshotUpdateEnv)); err == nil && v {
+		return true
+	}
+	if f := flag.Lookup("update"); f != nil {
+		if v, err := strconv.ParseBool(f.Value.String()); err == nil && v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package apitest
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func snapshotMux(body string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	})
+	return mux
+}
+
+func TestMatchSnapshot(t *testing.T) {
+	ignores := []string{"traceId", "timestamp", "data.list[*].createdAt"}
+
+	// 忽略易变字段
+	body := `{"code":0,"msg":"","data":{"id":1,"name":"jd","list":[{"title":"a","createdAt":"2024-05-01 10:00:00"},{"title":"b","createdAt":"2024-05-02 10:00:00"}]},"traceId":"0b1c","timestamp":1714528800}`
+	if err := NewAT("/user", http.MethodGet, "用户", nil, nil).
+		SetHandler(snapshotMux(body)).
+		SetParam(&struct{}{}).
+		Run().
+		MatchSnapshot("user", ignores...).
+		Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 逐个字段列出不同之处
+	body = `{"code":0,"msg":"","data":{"id":1.0,"name":"jc","extra":true,"list":[{"title":"a","createdAt":""}]},"traceId":"0b1c","timestamp":1}`
+	err := NewAT("/user", http.MethodGet, "用户", nil, nil).
+		SetHandler(snapshotMux(body)).
+		SetParam(&struct{}{}).
+		Run().
+		MatchSnapshot("user", ignores...).
+		Err()
+	if err == nil {
+		t.Fatal("want error, but got nil")
+	}
+	for _, want := range []string{
+		`data.name: have "jc", want "jd"`,
+		"data.extra: unexpected, have true",
+		"data.list: length 1, want 2",
+		`data.list[1]: missing, want {"title":"b"}`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q doesn't contain %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "data.id") || strings.Contains(err.Error(), "traceId") {
+		t.Errorf("error contains equal or ignored field: %v", err)
+	}
+}
+
+func TestUpdateSnapshot(t *testing.T) {
+	name := "update_test"
+	file := filepath.Join(snapshotDir, name+".xml")
+	t.Cleanup(func() { os.Remove(file) })
+
+	run := func(body string) error {
+		return NewAT("/user", http.MethodGet, "用户", nil, nil).
+			SetHandler(snapshotMux(body)).
+			UseXMLResultFormat().
+			SetParam(&struct{}{}).
+			Run().
+			MatchSnapshot(name).
+			Err()
+	}
+
+	// 不存在时自动创建
+	if err := run(`<user><name>jd</name></user>`); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "\n    <name>jd</name>") {
+		t.Fatalf("snapshot is not indented: %s", data)
+	}
+
+	if err := run(`<user><name>jc</name></user>`); err == nil || !strings.Contains(err.Error(), `name: have "jc", want "jd"`) {
+		t.Fatalf("bad error: %v", err)
+	}
+
+	t.Setenv(snapshotUpdateEnv, "1")
+	if err := run(`<user><name>jc</name></user>`); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(snapshotUpdateEnv, "")
+	if err := run(`<user><name>jc</name></user>`); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,5 +1,7 @@
 package testtype
 
+import "encoding/xml"
+
 type Inner struct {
 	Phone string `json:"phone"` // 手机
 }
@@ -22,3 +24,17 @@ type Addr struct {
 	City string `json:"city"` // 城市
 	Home string `json:"home"` // 家
 }
+
+type XMLUser struct {
+	XMLName xml.Name `xml:"user"`
+	Id      int      `xml:"id,attr"`                          // id
+	Name    string   `xml:"name"`                             // 名字
+	Tags    []string `xml:"tags>tag"`                         // 标签
+	Email   string   `xml:"http://example.com/contact email"` // 邮箱
+	Note    XMLNote  `xml:"note"`                             // 备注
+}
+
+type XMLNote struct {
+	Lang string `xml:"lang,attr"` // 语言
+	Text string `xml:",chardata"` // 内容
+}
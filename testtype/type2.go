@@ -78,3 +78,9 @@ type InnerModel struct {
 var (
 	_ = UserModel{}.password
 )
+
+// TreeNode 树节点，与apitest测试里的同名类型区分包名
+type TreeNode struct {
+	Title    string     `json:"title"` // 标题
+	Children []TreeNode `json:"children"`
+}
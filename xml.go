@@ -0,0 +1,215 @@
+package apitest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/donnol/do"
+)
+
+var (
+	xmlNameType = reflect.TypeOf(xml.Name{})
+
+	xmlAttrRegexp = regexp.MustCompile(`\s([^\s=/>]+)="`)
+)
+
+// xmlTag 解析后的xml tag，规则与encoding/xml相同
+type xmlTag struct {
+	ns      string   // 命名空间
+	name    string   // 元素名，属性为@name，文本为#text
+	parents []string // 包裹元素，如：a>b>c的a和b
+	attr    bool
+	text    bool
+	skip    bool
+}
+
+func parseXMLTag(field reflect.StructField) xmlTag {
+	var r xmlTag
+	tag := field.Tag.Get("xml")
+	if tag == "-" {
+		r.skip = true
+		return r
+	}
+	if i := strings.Index(tag, " "); i >= 0 {
+		r.ns, tag = tag[:i], tag[i+1:]
+	}
+
+	parts := strings.Split(tag, ",")
+	r.name = parts[0]
+	for _, opt := range parts[1:] {
+		switch opt {
+		case "attr":
+			r.attr = true
+		case "chardata", "cdata":
+			r.text = true
+			r.name = "#text"
+		case "innerxml":
+			r.text = true
+			r.name = "#innerxml"
+		case "comment":
+			r.skip = true
+		}
+	}
+	if r.text {
+		return r
+	}
+
+	if !r.attr && strings.Contains(r.name, ">") {
+		names := strings.Split(r.name, ">")
+		r.parents = names[:len(names)-1]
+		r.name = names[len(names)-1]
+	}
+	if r.name == "" {
+		r.name = xmlTypeName(field.Type)
+	}
+	if r.name == "" {
+		r.name = field.Name
+	}
+	if r.attr {
+		r.name = "@" + r.name
+	}
+	return r
+}
+
+// xmlTypeName 字段没有指定名字时，使用类型里XMLName指定的名字
+func xmlTypeName(typ reflect.Type) string {
+	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return ""
+	}
+	field, ok := typ.FieldByName("XMLName")
+	if !ok || field.Type != xmlNameType {
+		return ""
+	}
+	name := strings.Split(field.Tag.Get("xml"), ",")[0]
+	if i := strings.Index(name, " "); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// xmlFieldsToLine 与fieldsToLine相同，但字段名取自xml tag：属性为@name，文本为#text，a>b的包裹元素单独列出
+func xmlFieldsToLine(level int, fields []do.Field) (string, map[string]string) {
+	var lines string
+	var keyCommentMap = make(map[string]string)
+	var lastParents []string
+	for _, field := range fields {
+		if !field.StructField.IsExported() || field.StructField.Type == xmlNameType {
+			continue
+		}
+
+		// 内嵌结构体的字段视为外层的字段
+		if field.StructField.Anonymous && field.StructField.Tag.Get("xml") == "" {
+			innerLines, kcm := xmlFieldsToLine(level, field.Struct.Fields)
+			for k, v := range kcm {
+				keyCommentMap[k] = v
+			}
+			lines += innerLines
+			lastParents = nil
+			continue
+		}
+
+		tag := parseXMLTag(field.StructField)
+		if tag.skip {
+			continue
+		}
+
+		fieldType := field.StructField.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		fieldTypeName := "*" + typeNameOf(fieldType) + "*"
+		if tag.text {
+			fieldTypeName = "*string*"
+		}
+		if tag.ns != "" {
+			fieldTypeName += ", " + tag.ns
+		}
+
+		// 相邻字段共用的包裹元素只列出一次
+		var shared int
+		for shared < len(lastParents) && shared < len(tag.parents) && lastParents[shared] == tag.parents[shared] {
+			shared++
+		}
+		for i := shared; i < len(tag.parents); i++ {
+			lines += fmt.Sprintf("%s %s (*object*)\n", linePrefix(level+i), tag.parents[i])
+		}
+		lastParents = tag.parents
+
+		key := "|" + strings.Join(append(append([]string{}, tag.parents...), tag.name), "|")
+		keyCommentMap[key] = field.Comment
+
+		fieldLevel := level + len(tag.parents)
+		lines += fmt.Sprintf("%s %s (%s) %s\n", linePrefix(fieldLevel), tag.name, fieldTypeName, field.Comment)
+
+		if tag.attr || tag.text {
+			continue
+		}
+		switch fieldType.Kind() {
+		case reflect.Struct, reflect.Slice, reflect.Interface:
+			innerLines, kcm := xmlFieldsToLine(fieldLevel+1, field.Struct.Fields)
+			for k, v := range kcm {
+				keyCommentMap[key+k] = v
+			}
+			lines += innerLines
+		}
+	}
+
+	return lines, keyCommentMap
+}
+
+// xmlWithComment 在缩进后的xml每个元素后面加上注释，属性和文本的注释附在所在元素的后面
+func xmlWithComment(text string, kcm map[string]string) string {
+	var stack []string
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "</") {
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		}
+		if !strings.HasPrefix(trimmed, "<") || strings.HasPrefix(trimmed, "<?") || strings.HasPrefix(trimmed, "<!") {
+			continue
+		}
+
+		name := trimmed[1:]
+		if i := strings.IndexAny(name, " \t/>"); i >= 0 {
+			name = name[:i]
+		}
+		closed := strings.HasSuffix(trimmed, "/>") || strings.Contains(trimmed, "</"+name+">")
+
+		// 根元素对应结构体本身，没有注释
+		var key string
+		var comments []string
+		if len(stack) > 0 {
+			key = "|" + strings.Join(append(append([]string{}, stack[1:]...), name), "|")
+			if c := kcm[key]; c != "" {
+				comments = append(comments, c)
+			}
+		}
+		for _, m := range xmlAttrRegexp.FindAllStringSubmatch(trimmed, -1) {
+			if c := kcm[key+"|@"+m[1]]; c != "" {
+				comments = append(comments, "@"+m[1]+": "+c)
+			}
+		}
+		if closed {
+			if c := kcm[key+"|#text"]; c != "" {
+				comments = append(comments, "#text: "+c)
+			}
+		} else {
+			stack = append(stack, name)
+		}
+
+		if len(comments) > 0 {
+			lines[i] = line + " <!-- " + strings.Join(comments, "; ") + " -->"
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,102 @@
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/donnol/apitest/testtype"
+)
+
+func TestUseXMLParamFormat(t *testing.T) {
+	at := NewAT("/xml", http.MethodPost, "xml", nil, nil).UseXMLParamFormat()
+	if at.paramFormat != "xml" || at.resultFormat != "" {
+		t.Fatalf("bad format, param: %q, result: %q", at.paramFormat, at.resultFormat)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/xml", func(w http.ResponseWriter, r *http.Request) {
+		var note testtype.XMLNote
+		if err := xml.NewDecoder(r.Body).Decode(&note); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"lang": note.Lang, "text": note.Text})
+	})
+	var r struct {
+		Lang string `json:"lang"`
+		Text string `json:"text"`
+	}
+	if err := at.SetHandler(mux).
+		SetParam(&testtype.XMLNote{Lang: "zh", Text: "hi"}).
+		Run().
+		EqualCode(http.StatusOK).
+		Result(&r).
+		Err(); err != nil {
+		t.Fatal(err)
+	}
+	if r.Lang != "zh" || r.Text != "hi" {
+		t.Fatalf("bad result: %+v", r)
+	}
+}
+
+func TestXMLIndent(t *testing.T) {
+	buf := new(bytes.Buffer)
+	XMLIndent(buf, testtype.XMLNote{Lang: "zh", Text: "hi"})
+	if want := `<XMLNote lang="zh">hi</XMLNote>`; !strings.Contains(buf.String(), want) {
+		t.Fatalf("have %s, want %s", buf, want)
+	}
+}
+
+func TestXMLStructToBlock(t *testing.T) {
+	v := &testtype.XMLUser{
+		Id:    1,
+		Name:  "jd",
+		Tags:  []string{"a", "b"},
+		Email: "jd@example.com",
+		Note:  testtype.XMLNote{Lang: "zh", Text: "hi"},
+	}
+	block, kcm, err := structToBlock(returnName, "", "xml", v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantLines := "* @id (*int*) id\n" +
+		"* name (*string*) 名字\n" +
+		"* tags (*object*)\n" +
+		"    * tag (*string list*) 标签\n" +
+		"* email (*string*, http://example.com/contact) 邮箱\n" +
+		"* note (*object*) 备注\n" +
+		"    * @lang (*string*) 语言\n" +
+		"    * #text (*string*) 内容\n"
+	if !strings.HasSuffix(block, wantLines+"\n") {
+		t.Fatalf("bad block: %s", block)
+	}
+	if kcm["|tags|tag"] != "标签" || kcm["|note|@lang"] != "语言" || kcm["|note|#text"] != "内容" {
+		t.Fatalf("bad kcm: %v", kcm)
+	}
+	if !strings.Contains(block, `style="display:none;"><user id="1">`) {
+		t.Fatalf("copy text should be xml: %s", block)
+	}
+
+	data, err := xml.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary := dataToSummary(returnName, data, "xml", true, kcm)
+	for _, want := range []string{
+		"```xml\n<user id=\"1\"> <!-- @id: id -->\n",
+		"    <name>jd</name> <!-- 名字 -->\n",
+		"    <tags>\n        <tag>a</tag> <!-- 标签 -->\n        <tag>b</tag> <!-- 标签 -->\n    </tags>\n",
+		"    <email xmlns=\"http://example.com/contact\">jd@example.com</email> <!-- 邮箱 -->\n",
+		"    <note lang=\"zh\">hi</note> <!-- 备注; @lang: 语言; #text: 内容 -->\n",
+		"</user>\n```",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Fatalf("summary should contain %q, have:\n%s", want, summary)
+		}
+	}
+}